@@ -0,0 +1,180 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+	"gopkg.in/yaml.v3"
+)
+
+var globalContentType string
+var globalContentEncoding string
+var globalCacheControl string
+var globalContentDisposition string
+var globalExpires string
+var globalMetadata []string
+var globalAttributesFile string
+
+// attributeRule overrides attributes of uploaded files matching pattern, read from the attributes file
+type attributeRule struct {
+	// glob pattern, patterns without a slash match the file name, others the path relative to the copied
+	// directory or prefix
+	Pattern            string            `yaml:"pattern"`
+	ContentType        string            `yaml:"content-type"`
+	ContentEncoding    string            `yaml:"content-encoding"`
+	CacheControl       string            `yaml:"cache-control"`
+	ContentDisposition string            `yaml:"content-disposition"`
+	Expires            string            `yaml:"expires"`
+	Metadata           map[string]string `yaml:"metadata"`
+
+	expires *time.Time
+}
+
+// matches reports whether the rule applies to file or object at slash separated relative path p
+func (r attributeRule) matches(p string) bool {
+	if !strings.Contains(r.Pattern, "/") {
+		p = path.Base(p)
+	}
+	ok, _ := path.Match(r.Pattern, p)
+	return ok
+}
+
+func (r attributeRule) empty() bool {
+	return len(r.ContentType) == 0 && len(r.ContentEncoding) == 0 && len(r.CacheControl) == 0 &&
+		len(r.ContentDisposition) == 0 && len(r.Expires) == 0 && len(r.Metadata) == 0
+}
+
+// set non empty values of the rule on attrs, metadata keys are merged
+func (r attributeRule) apply(attrs *s3transfer.ObjectAttributes) {
+	if len(r.ContentType) != 0 {
+		attrs.ContentType = r.ContentType
+	}
+	if len(r.ContentEncoding) != 0 {
+		attrs.ContentEncoding = r.ContentEncoding
+	}
+	if len(r.CacheControl) != 0 {
+		attrs.CacheControl = r.CacheControl
+	}
+	if len(r.ContentDisposition) != 0 {
+		attrs.ContentDisposition = r.ContentDisposition
+	}
+	if r.expires != nil {
+		attrs.Expires = r.expires
+	}
+	if len(r.Metadata) != 0 {
+		merged := make(map[string]string, len(attrs.Metadata)+len(r.Metadata))
+		for k, v := range attrs.Metadata {
+			merged[k] = v
+		}
+		for k, v := range r.Metadata {
+			merged[k] = v
+		}
+		attrs.Metadata = merged
+	}
+}
+
+// attributes of uploaded files from flags, rules of the attributes file are applied in order on top of flags.
+// returns nil when no attribute is given, content type is detected in that case
+func newUploadAttributes() (func(string) s3transfer.ObjectAttributes, error) {
+	flags := attributeRule{
+		Pattern:            "*",
+		ContentType:        globalContentType,
+		ContentEncoding:    globalContentEncoding,
+		CacheControl:       globalCacheControl,
+		ContentDisposition: globalContentDisposition,
+		Expires:            globalExpires,
+	}
+
+	var err error
+	if flags.Metadata, err = parseMetadata(globalMetadata); err != nil {
+		return nil, err
+	}
+
+	rules := []attributeRule{flags}
+	if len(globalAttributesFile) != 0 {
+		fileRules, err := readAttributesFile(globalAttributesFile)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, fileRules...)
+	}
+
+	for i := range rules {
+		if rules[i].expires, err = parseExpires(rules[i].Expires); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(rules) == 1 && flags.empty() {
+		return nil, nil
+	}
+
+	return func(p string) s3transfer.ObjectAttributes {
+		var attrs s3transfer.ObjectAttributes
+		for _, r := range rules {
+			if r.matches(p) {
+				r.apply(&attrs)
+			}
+		}
+		return attrs
+	}, nil
+}
+
+// parse repeated k=v metadata flags
+func parseMetadata(values []string) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	metadata := make(map[string]string, len(values))
+	for _, v := range values {
+		k, val, ok := strings.Cut(v, "=")
+		if !ok || len(k) == 0 {
+			return nil, fmt.Errorf("metadata should be key=value, got %q", v)
+		}
+		metadata[k] = val
+	}
+	return metadata, nil
+}
+
+// parse expires as RFC3339 or HTTP date
+func parseExpires(s string) (*time.Time, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+
+	for _, layout := range []string{time.RFC3339, http.TimeFormat} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, fmt.Errorf("expires should be RFC3339 or HTTP date, got %q", s)
+}
+
+// read list of attribute rules from a YAML file
+func readAttributesFile(p string) ([]attributeRule, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read attributes file %w", err)
+	}
+
+	var rules []attributeRule
+	if err := yaml.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("cannot parse attributes file %s: %w", p, err)
+	}
+
+	for _, r := range rules {
+		if _, err := path.Match(r.Pattern, ""); err != nil || len(r.Pattern) == 0 {
+			return nil, fmt.Errorf("invalid pattern %q in attributes file %s", r.Pattern, p)
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttributeRuleMatches(t *testing.T) {
+	cases := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.html", "site/index.html", true},
+		{"*.html", "index.htm", false},
+		{"assets/*.js", "assets/app.js", true},
+		{"assets/*.js", "site/assets/app.js", false},
+		{"*/assets/*.js", "site/assets/app.js", true},
+		{"static/*.css", "static/x.css", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.pattern+" "+c.path, func(t *testing.T) {
+			if got := (attributeRule{Pattern: c.pattern}).matches(c.path); got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseMetadata(t *testing.T) {
+	got, err := parseMetadata([]string{"team=web", "note=a=b", "empty="})
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"team": "web", "note": "a=b", "empty": ""}, got)
+
+	for _, input := range []string{"team", "=web"} {
+		_, err := parseMetadata([]string{input})
+		require.Error(t, err, input)
+	}
+}
+
+func TestParseExpires(t *testing.T) {
+	want := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	for _, input := range []string{"2025-01-02T03:04:05Z", "Thu, 02 Jan 2025 03:04:05 GMT"} {
+		got, err := parseExpires(input)
+		require.NoError(t, err, input)
+		require.True(t, want.Equal(*got), input)
+	}
+
+	_, err := parseExpires("tomorrow")
+	require.Error(t, err)
+}
+
+func TestNewUploadAttributes(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "attributes.yaml")
+	require.NoError(t, os.WriteFile(file, []byte(`
+- pattern: "*.html"
+  cache-control: no-cache
+  metadata:
+    page: "true"
+- pattern: "*.gz"
+  content-encoding: gzip
+  content-type: application/json
+`), 0644))
+
+	defer func(cacheControl string, metadata []string, attributesFile string) {
+		globalCacheControl, globalMetadata, globalAttributesFile = cacheControl, metadata, attributesFile
+	}(globalCacheControl, globalMetadata, globalAttributesFile)
+	globalCacheControl = "max-age=3600"
+	globalMetadata = []string{"team=web"}
+	globalAttributesFile = file
+
+	attributes, err := newUploadAttributes()
+	require.NoError(t, err)
+
+	cases := []struct {
+		path string
+		want s3transfer.ObjectAttributes
+	}{
+		{"index.html", s3transfer.ObjectAttributes{CacheControl: "no-cache", Metadata: map[string]string{"team": "web", "page": "true"}}},
+		{"data.gz", s3transfer.ObjectAttributes{CacheControl: "max-age=3600", ContentEncoding: "gzip", ContentType: "application/json", Metadata: map[string]string{"team": "web"}}},
+		{"image.png", s3transfer.ObjectAttributes{CacheControl: "max-age=3600", Metadata: map[string]string{"team": "web"}}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.path, func(t *testing.T) {
+			require.Equal(t, c.want, attributes(c.path))
+		})
+	}
+}
+
+func TestNewUploadAttributesWithoutFlags(t *testing.T) {
+	attributes, err := newUploadAttributes()
+	require.NoError(t, err)
+	require.Nil(t, attributes)
+}
@@ -0,0 +1,26 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+var globalChecksum string
+
+// checksum algorithm from flags, given in lowercase i.e. crc32c
+func newChecksumAlgorithm() (types.ChecksumAlgorithm, error) {
+	if len(globalChecksum) == 0 {
+		return "", nil
+	}
+
+	algorithm := types.ChecksumAlgorithm(strings.ToUpper(globalChecksum))
+	if err := s3transfer.ValidateChecksumAlgorithm(algorithm); err != nil {
+		return "", err
+	}
+	return algorithm, nil
+}
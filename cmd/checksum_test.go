@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChecksumAlgorithm(t *testing.T) {
+	defer func(checksum string) { globalChecksum = checksum }(globalChecksum)
+
+	cases := []struct {
+		name     string
+		checksum string
+		want     types.ChecksumAlgorithm
+		wantErr  bool
+	}{
+		{"disabled", "", "", false},
+		{"lowercase", "crc32c", types.ChecksumAlgorithmCrc32c, false},
+		{"uppercase", "SHA256", types.ChecksumAlgorithmSha256, false},
+		{"unknown", "md5", "", true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			globalChecksum = c.checksum
+			got, err := newChecksumAlgorithm()
+			if c.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+// clientOptions holds the settings used for creating s3 clients
+type clientOptions struct {
+	endpoint        string
+	profile         string
+	region          string
+	accessKey       string
+	secretKey       string
+	sessionToken    string
+	credentialsFile string
+	noSignRequest   bool
+	roleARN         string
+	externalID      string
+	mfaSerial       string
+	roleSessionName string
+	// nil means path style addressing for custom endpoints and virtual hosted style for aws
+	pathStyle     *bool
+	useDualStack  bool
+	useFIPS       bool
+	useAccelerate bool
+	// log HTTP requests and responses of the SDK, without bodies
+	logWire bool
+	transportOptions
+}
+
+var globalClientOptions clientOptions
+
+// create transfer client for alias from configuration file, empty alias uses command line settings only
+func newClient(ctx context.Context, alias string, opts s3transfer.Options) (*s3transfer.Client, error) {
+	api, err := newS3API(ctx, globalClientOptions, alias)
+	if err != nil {
+		return nil, err
+	}
+	client := s3transfer.New(api, opts)
+	commandMetrics.addClient(client)
+	return client, nil
+}
+
+// create s3 client with alias settings applied on top of the given options
+func newS3API(ctx context.Context, opts clientOptions, alias string) (*s3.Client, error) {
+	opts, err := opts.withAlias(alias)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := opts.loadConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(cfg, func(o *s3.Options) {
+		opts.applyS3Options(o)
+		o.APIOptions = append(o.APIOptions, requestRetries.addMiddleware)
+		if commandMetrics != nil {
+			o.APIOptions = append(o.APIOptions, commandMetrics.addMiddleware)
+		}
+		if commandRequestLimiter != nil {
+			o.APIOptions = append(o.APIOptions, commandRequestLimiter.addMiddleware)
+		}
+		if logger := slog.Default(); logger.Enabled(ctx, slog.LevelInfo) {
+			o.APIOptions = append(o.APIOptions, requestLogger{logger: logger}.addMiddleware)
+		}
+	})
+	return client, nil
+}
+
+// transfer options from global flags
+func newTransferOptions() (s3transfer.Options, error) {
+	opts := s3transfer.Options{
+		Concurrency:    globalMaxParallelRequests,
+		RequestTimeout: globalRequestTimeout,
+	}
+
+	var err error
+	if opts.LimitRate, err = parseRateFlag(globalLimitRate); err != nil {
+		return opts, fmt.Errorf("limit-rate: %w", err)
+	}
+	if opts.LimitUploadRate, err = parseRateFlag(globalLimitUploadRate); err != nil {
+		return opts, fmt.Errorf("limit-upload-rate: %w", err)
+	}
+	if opts.LimitDownloadRate, err = parseRateFlag(globalLimitDownloadRate); err != nil {
+		return opts, fmt.Errorf("limit-download-rate: %w", err)
+	}
+	if opts.Encryption, err = newEncryption(); err != nil {
+		return opts, err
+	}
+	if opts.ClientEncryption, err = newClientEncryption(); err != nil {
+		return opts, err
+	}
+	if opts.Attributes, err = newUploadAttributes(); err != nil {
+		return opts, err
+	}
+	if opts.Storage, err = newStorageOptions(); err != nil {
+		return opts, err
+	}
+	if opts.MetadataDirective, err = newMetadataDirective(); err != nil {
+		return opts, err
+	}
+	if opts.Archived, err = newArchivedPolicy(); err != nil {
+		return opts, err
+	}
+	if opts.Restore, err = newRestoreParams(); err != nil {
+		return opts, err
+	}
+	if opts.Overwrite, err = newOverwritePolicy(); err != nil {
+		return opts, err
+	}
+	if opts.BackupSuffix, err = newBackupSuffix(); err != nil {
+		return opts, err
+	}
+	if opts.Checksum, err = newChecksumAlgorithm(); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+func (o clientOptions) validate() error {
+	if (len(o.accessKey) == 0) != (len(o.secretKey) == 0) {
+		return errors.New("access-key and secret-key should be given together")
+	}
+
+	if len(o.sessionToken) != 0 && len(o.accessKey) == 0 {
+		return errors.New("session-token requires access-key and secret-key")
+	}
+
+	if o.noSignRequest && (len(o.accessKey) != 0 || len(o.roleARN) != 0) {
+		return errors.New("no-sign-request cannot be used with credentials or role assumption")
+	}
+
+	if len(o.roleARN) == 0 && (len(o.externalID) != 0 || len(o.mfaSerial) != 0) {
+		return errors.New("external-id and mfa-serial require role-arn")
+	}
+
+	if o.useAccelerate && (len(o.endpoint) != 0 || (o.pathStyle != nil && *o.pathStyle)) {
+		return errors.New("use-accelerate cannot be used with custom endpoints or path style addressing")
+	}
+
+	if o.useFIPS && len(o.endpoint) != 0 {
+		return errors.New("use-fips cannot be used with custom endpoints")
+	}
+
+	return nil
+}
+
+// apply endpoint settings to s3 client options, endpoints are resolved by the SDK's S3 specific resolver
+func (o clientOptions) applyS3Options(options *s3.Options) {
+	if len(o.endpoint) != 0 {
+		options.BaseEndpoint = aws.String(o.endpoint)
+		// most S3 compatible services do not support virtual hosted style addressing
+		options.UsePathStyle = true
+	}
+
+	if o.pathStyle != nil {
+		options.UsePathStyle = *o.pathStyle
+	}
+
+	if o.useDualStack {
+		options.EndpointOptions.UseDualStackEndpoint = aws.DualStackEndpointStateEnabled
+	}
+
+	if o.useFIPS {
+		options.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+	}
+
+	options.UseAccelerate = o.useAccelerate
+}
+
+// load aws config with the given options applied on top of the default credential chain
+func (o clientOptions) loadConfig(ctx context.Context) (aws.Config, error) {
+	if err := o.validate(); err != nil {
+		return aws.Config{}, err
+	}
+
+	optionFuncs := make([]func(*config.LoadOptions) error, 0)
+	if len(o.profile) != 0 {
+		optionFuncs = append(optionFuncs, config.WithSharedConfigProfile(o.profile))
+	}
+
+	if len(o.region) != 0 {
+		optionFuncs = append(optionFuncs, config.WithRegion(o.region))
+	}
+
+	if len(o.credentialsFile) != 0 {
+		optionFuncs = append(optionFuncs, config.WithSharedCredentialsFiles([]string{o.credentialsFile}))
+	}
+
+	httpClient, err := o.httpClient()
+	if err != nil {
+		return aws.Config{}, err
+	}
+	if httpClient != nil {
+		optionFuncs = append(optionFuncs, config.WithHTTPClient(httpClient))
+	}
+
+	if o.logWire {
+		optionFuncs = append(optionFuncs,
+			config.WithClientLogMode(aws.LogRequest|aws.LogResponse|aws.LogRetries),
+			config.WithLogger(sdkLogger{logger: slog.Default()}))
+	}
+
+	switch {
+	case o.noSignRequest:
+		optionFuncs = append(optionFuncs, config.WithCredentialsProvider(aws.AnonymousCredentials{}))
+	case len(o.accessKey) != 0:
+		optionFuncs = append(optionFuncs, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(o.accessKey, o.secretKey, o.sessionToken)))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, optionFuncs...)
+	if err != nil {
+		return cfg, fmt.Errorf("cannot read config %w", err)
+	}
+
+	if len(o.roleARN) != 0 {
+		// credentials loaded above are used for calling sts
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(cfg), o.roleARN, func(options *stscreds.AssumeRoleOptions) {
+			if len(o.roleSessionName) != 0 {
+				options.RoleSessionName = o.roleSessionName
+			}
+			if len(o.externalID) != 0 {
+				options.ExternalID = aws.String(o.externalID)
+			}
+			if len(o.mfaSerial) != 0 {
+				options.SerialNumber = aws.String(o.mfaSerial)
+				options.TokenProvider = stscreds.StdinTokenProvider
+			}
+		})
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+
+	return cfg, nil
+}
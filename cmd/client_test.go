@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientOptionsValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   clientOptions
+		wantErr bool
+	}{
+		{"defaults", clientOptions{}, false},
+		{"static credentials", clientOptions{accessKey: "a", secretKey: "s"}, false},
+		{"access key without secret", clientOptions{accessKey: "a"}, true},
+		{"session token without keys", clientOptions{sessionToken: "t"}, true},
+		{"anonymous with credentials", clientOptions{noSignRequest: true, accessKey: "a", secretKey: "s"}, true},
+		{"anonymous with role", clientOptions{noSignRequest: true, roleARN: "arn:aws:iam::123456789012:role/r"}, true},
+		{"external id without role", clientOptions{externalID: "id"}, true},
+		{"role with mfa", clientOptions{roleARN: "arn:aws:iam::123456789012:role/r", mfaSerial: "serial"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.input.validate()
+			if c.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigCredentials(t *testing.T) {
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	opts := clientOptions{region: "eu-west-1", accessKey: "AKID", secretKey: "SECRET", sessionToken: "TOKEN"}
+	cfg, err := opts.loadConfig(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "eu-west-1", cfg.Region)
+
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, "AKID", creds.AccessKeyID)
+	require.Equal(t, "SECRET", creds.SecretAccessKey)
+	require.Equal(t, "TOKEN", creds.SessionToken)
+}
+
+func TestLoadConfigNoSignRequest(t *testing.T) {
+	t.Setenv("AWS_CONFIG_FILE", "/dev/null")
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", "/dev/null")
+
+	opts := clientOptions{noSignRequest: true}
+	cfg, err := opts.loadConfig(context.Background())
+	require.NoError(t, err)
+	require.True(t, aws.IsCredentialsProvider(cfg.Credentials, aws.AnonymousCredentials{}))
+}
+
+// httpClient that records requested url and fails the request
+type urlRecordingClient struct {
+	url string
+}
+
+var errRecorded = errors.New("recorded")
+
+func (c *urlRecordingClient) Do(req *http.Request) (*http.Response, error) {
+	c.url = req.URL.String()
+	return nil, errRecorded
+}
+
+func TestApplyS3OptionsEndpoints(t *testing.T) {
+	cases := []struct {
+		name  string
+		input clientOptions
+		want  string
+	}{
+		{"aws default", clientOptions{}, "https://mybucket.s3.eu-west-1.amazonaws.com/my/key"},
+		{"aws path style", clientOptions{pathStyle: aws.Bool(true)}, "https://s3.eu-west-1.amazonaws.com/mybucket/my/key"},
+		{"aws dual stack", clientOptions{useDualStack: true}, "https://mybucket.s3.dualstack.eu-west-1.amazonaws.com/my/key"},
+		{"aws fips", clientOptions{useFIPS: true}, "https://mybucket.s3-fips.eu-west-1.amazonaws.com/my/key"},
+		{"aws accelerate", clientOptions{useAccelerate: true}, "https://mybucket.s3-accelerate.amazonaws.com/my/key"},
+		{"custom endpoint", clientOptions{endpoint: "http://minio.local:9000"}, "http://minio.local:9000/mybucket/my/key"},
+		{"custom endpoint with path", clientOptions{endpoint: "https://gateway.local/s3"}, "https://gateway.local/s3/mybucket/my/key"},
+		{"custom endpoint virtual host", clientOptions{endpoint: "https://ceph.local", pathStyle: aws.Bool(false)}, "https://mybucket.ceph.local/my/key"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recorder := &urlRecordingClient{}
+			options := s3.Options{
+				Region:      "eu-west-1",
+				Credentials: aws.AnonymousCredentials{},
+				HTTPClient:  recorder,
+				Retryer:     aws.NopRetryer{},
+			}
+			c.input.applyS3Options(&options)
+
+			_, err := s3.New(options).HeadObject(context.Background(), &s3.HeadObjectInput{
+				Bucket: aws.String("mybucket"),
+				Key:    aws.String("my/key"),
+			})
+			require.ErrorIs(t, err, errRecorded)
+
+			if recorder.url != c.want {
+				t.Errorf("got %v want %v", recorder.url, c.want)
+			}
+		})
+	}
+}
+
+func TestClientOptionsValidateEndpoints(t *testing.T) {
+	require.Error(t, clientOptions{endpoint: "http://minio.local", useAccelerate: true}.validate())
+	require.Error(t, clientOptions{pathStyle: aws.Bool(true), useAccelerate: true}.validate())
+	require.Error(t, clientOptions{endpoint: "http://minio.local", useFIPS: true}.validate())
+	require.NoError(t, clientOptions{useFIPS: true, useDualStack: true}.validate())
+}
@@ -0,0 +1,198 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+// aliasConfig defines a named S3 endpoint with its own settings, used as minio1://bucket/key or s3://minio1@bucket/key
+type aliasConfig struct {
+	Endpoint        string `yaml:"endpoint"`
+	Region          string `yaml:"region"`
+	Profile         string `yaml:"profile"`
+	AccessKey       string `yaml:"access-key"`
+	SecretKey       string `yaml:"secret-key"`
+	SessionToken    string `yaml:"session-token"`
+	CredentialsFile string `yaml:"credentials-file"`
+	NoSignRequest   bool   `yaml:"no-sign-request"`
+	PathStyle       *bool  `yaml:"path-style"`
+
+	CABundle           string `yaml:"ca-bundle"`
+	InsecureSkipVerify bool   `yaml:"insecure-skip-verify"`
+	Proxy              string `yaml:"proxy"`
+}
+
+// fileConfig is the content of s3cli configuration file
+type fileConfig struct {
+	// default values for command line flags, keyed by flag name i.e. max-parallel-requests: 20
+	Defaults map[string]string      `yaml:"defaults"`
+	Aliases  map[string]aliasConfig `yaml:"aliases"`
+}
+
+var globalConfigFile string
+var globalConfig fileConfig
+
+var aliasNamePattern = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_-]*$`)
+
+var errUnknownAlias = errors.New("unknown alias")
+
+// default configuration file path i.e. ~/.config/s3cli/config.yaml
+func defaultConfigFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "s3cli", "config.yaml")
+}
+
+// read configuration file, missing default file is not an error
+func readConfigFile(path string, required bool) (fileConfig, error) {
+	var cfg fileConfig
+	if len(path) == 0 {
+		return cfg, nil
+	}
+
+	b, err := os.ReadFile(path)
+	if os.IsNotExist(err) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("cannot read config file %w", err)
+	}
+
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return cfg, fmt.Errorf("cannot parse config file %s: %w", path, err)
+	}
+
+	for name := range cfg.Aliases {
+		if !aliasNamePattern.MatchString(name) || name+"://" == s3prefix {
+			return cfg, fmt.Errorf("invalid alias name %q in config file %s", name, path)
+		}
+	}
+
+	return cfg, nil
+}
+
+// load configuration file and apply its defaults to flags not given on the command line
+func loadConfigFile(cmd *cobra.Command, _ []string) error {
+	path, required := globalConfigFile, true
+	if len(path) == 0 {
+		path, required = defaultConfigFile(), false
+	}
+
+	cfg, err := readConfigFile(path, required)
+	if err != nil {
+		return err
+	}
+
+	for name, value := range cfg.Defaults {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil {
+			// flag belongs to another command
+			continue
+		}
+
+		if flag.Changed {
+			continue
+		}
+
+		if err := flag.Value.Set(value); err != nil {
+			return fmt.Errorf("invalid default for %s in config file: %w", name, err)
+		}
+	}
+
+	globalConfig = cfg
+	return nil
+}
+
+// split alias from s3 path, i.e. minio1://bucket/key and s3://minio1@bucket/key are both
+// resolved to alias minio1 and path s3://bucket/key. Paths without alias are returned as is.
+func splitAlias(path string) (alias, s3path string) {
+	if strings.HasPrefix(path, s3prefix) {
+		rest := path[s3prefixLen:]
+		bucket := rest
+		if idx := strings.IndexRune(rest, '/'); idx != -1 {
+			bucket = rest[:idx]
+		}
+
+		// bucket names cannot contain @
+		if idx := strings.IndexRune(bucket, '@'); idx != -1 {
+			return rest[:idx], s3prefix + rest[idx+1:]
+		}
+		return "", path
+	}
+
+	idx := strings.Index(path, "://")
+	if idx == -1 {
+		return "", path
+	}
+
+	if _, ok := globalConfig.Aliases[path[:idx]]; !ok {
+		return "", path
+	}
+	return path[:idx], s3prefix + path[idx+len("://"):]
+}
+
+// check whether path refers to S3, either with s3:// or a configured alias
+func isS3Path(path string) bool {
+	_, s3path := splitAlias(path)
+	return strings.HasPrefix(s3path, s3prefix)
+}
+
+// group paths by their alias, paths without alias are grouped under empty string
+func groupByAlias(paths []string) map[string][]string {
+	groups := make(map[string][]string)
+	for _, p := range paths {
+		alias, _ := splitAlias(p)
+		groups[alias] = append(groups[alias], p)
+	}
+	return groups
+}
+
+// client options for alias, settings given on the command line take precedence over the alias
+func (o clientOptions) withAlias(alias string) (clientOptions, error) {
+	if len(alias) == 0 {
+		return o, nil
+	}
+
+	a, ok := globalConfig.Aliases[alias]
+	if !ok {
+		return o, fmt.Errorf("%w %s", errUnknownAlias, alias)
+	}
+
+	setIfEmpty := func(value *string, aliasValue string) {
+		if len(*value) == 0 {
+			*value = aliasValue
+		}
+	}
+
+	setIfEmpty(&o.endpoint, a.Endpoint)
+	setIfEmpty(&o.region, a.Region)
+	setIfEmpty(&o.profile, a.Profile)
+	setIfEmpty(&o.credentialsFile, a.CredentialsFile)
+	setIfEmpty(&o.caBundle, a.CABundle)
+	setIfEmpty(&o.proxy, a.Proxy)
+	o.insecureSkipVerify = o.insecureSkipVerify || a.InsecureSkipVerify
+
+	// credentials are taken as a whole so that keys of different sources are not mixed
+	if len(o.accessKey) == 0 && !o.noSignRequest {
+		o.accessKey, o.secretKey, o.sessionToken = a.AccessKey, a.SecretKey, a.SessionToken
+		o.noSignRequest = a.NoSignRequest
+	}
+
+	if o.pathStyle == nil {
+		o.pathStyle = a.PathStyle
+	}
+
+	return o, nil
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+const testConfigFile = `
+defaults:
+  max-parallel-requests: 25
+aliases:
+  minio1:
+    endpoint: https://minio1.example.com
+    region: us-east-1
+    access-key: minio
+    secret-key: minio123
+    path-style: true
+  ceph:
+    endpoint: http://ceph.local:7480
+`
+
+func writeTestConfig(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0600))
+	return path
+}
+
+func useTestConfig(t *testing.T) {
+	cfg, err := readConfigFile(writeTestConfig(t, testConfigFile), true)
+	require.NoError(t, err)
+
+	old := globalConfig
+	globalConfig = cfg
+	t.Cleanup(func() { globalConfig = old })
+}
+
+func TestSplitAlias(t *testing.T) {
+	useTestConfig(t)
+
+	cases := []struct {
+		name      string
+		input     string
+		wantAlias string
+		wantPath  string
+	}{
+		{"plain s3 path", "s3://bucket/key", "", "s3://bucket/key"},
+		{"alias as scheme", "minio1://bucket/key", "minio1", "s3://bucket/key"},
+		{"alias in s3 path", "s3://ceph@bucket/key", "ceph", "s3://bucket/key"},
+		{"alias in s3 path without key", "s3://ceph@bucket", "ceph", "s3://bucket"},
+		{"at sign in key", "s3://bucket/foo@bar", "", "s3://bucket/foo@bar"},
+		{"unknown scheme", "http://bucket/key", "", "http://bucket/key"},
+		{"local path", "/tmp/test.txt", "", "/tmp/test.txt"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gotAlias, gotPath := splitAlias(c.input)
+			if gotAlias != c.wantAlias {
+				t.Errorf("got %v want %v", gotAlias, c.wantAlias)
+			}
+
+			if gotPath != c.wantPath {
+				t.Errorf("got %v want %v", gotPath, c.wantPath)
+			}
+		})
+	}
+}
+
+func TestExtractKeyAndBucketWithAlias(t *testing.T) {
+	useTestConfig(t)
+
+	bucket, key, err := extractBucketAndKey("minio1://mybucket/mykey")
+	require.NoError(t, err)
+	require.Equal(t, "mybucket", bucket)
+	require.Equal(t, "mykey", key)
+}
+
+func TestReadConfigFileForError(t *testing.T) {
+	_, err := readConfigFile(filepath.Join(t.TempDir(), "missing.yaml"), true)
+	require.Error(t, err)
+
+	cfg, err := readConfigFile(filepath.Join(t.TempDir(), "missing.yaml"), false)
+	require.NoError(t, err)
+	require.Empty(t, cfg.Aliases)
+
+	_, err = readConfigFile(writeTestConfig(t, "aliases:\n  s3:\n    endpoint: http://localhost\n"), true)
+	require.Error(t, err, "s3 cannot be used as alias")
+}
+
+func TestClientOptionsWithAlias(t *testing.T) {
+	useTestConfig(t)
+
+	opts, err := clientOptions{region: "eu-west-1"}.withAlias("minio1")
+	require.NoError(t, err)
+	require.Equal(t, "https://minio1.example.com", opts.endpoint)
+	require.Equal(t, "eu-west-1", opts.region, "command line settings should take precedence")
+	require.Equal(t, "minio", opts.accessKey)
+	require.Equal(t, "minio123", opts.secretKey)
+	require.Equal(t, aws.Bool(true), opts.pathStyle)
+
+	opts, err = clientOptions{accessKey: "a", secretKey: "s"}.withAlias("minio1")
+	require.NoError(t, err)
+	require.Equal(t, "a", opts.accessKey)
+	require.Equal(t, "s", opts.secretKey)
+
+	_, err = clientOptions{}.withAlias("unknown")
+	require.ErrorIs(t, err, errUnknownAlias)
+}
+
+func TestLoadConfigFileDefaults(t *testing.T) {
+	oldConfig, oldConfigFile := globalConfig, globalConfigFile
+	t.Cleanup(func() { globalConfig, globalConfigFile = oldConfig, oldConfigFile })
+	globalConfigFile = writeTestConfig(t, testConfigFile)
+
+	var parallel, other int
+	cmd := &cobra.Command{}
+	cmd.Flags().IntVar(&parallel, "max-parallel-requests", 10, "")
+	cmd.Flags().IntVar(&other, "other", 10, "")
+
+	require.NoError(t, loadConfigFile(cmd, nil))
+	require.Equal(t, 25, parallel)
+	require.Equal(t, 10, other)
+	require.Contains(t, globalConfig.Aliases, "minio1")
+
+	// flags given on command line are not overridden
+	require.NoError(t, cmd.Flags().Set("max-parallel-requests", "5"))
+	require.NoError(t, loadConfigFile(cmd, nil))
+	require.Equal(t, 5, parallel)
+}
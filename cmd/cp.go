@@ -5,17 +5,11 @@ package cmd
 
 import (
 	"context"
-	"errors"
 	"fmt"
-	"io"
-	"io/fs"
 	"os"
-	"path/filepath"
-	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
 	"github.com/spf13/cobra"
 )
 
@@ -25,343 +19,146 @@ var cpCmd = &cobra.Command{
 	Short: "Copy from/to S3",
 	Args:  cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
-		executeCp(args)
+		executeCp(cmd.Context(), args)
 	},
 }
 
 var globalFlatten bool
+var globalPreserve bool
+var globalNoProgress bool
+var globalSourceEndpoint string
+var globalDestEndpoint string
 
 func init() {
 	rootCmd.AddCommand(cpCmd)
 	cpCmd.Flags().BoolVarP(&globalFlatten, "flatten", "f", false, "flatten directory tree")
-}
-
-type s3CopyClient interface {
-	copyFromS3ToS3(src, dest string) error
-	copyFromS3ToLocal(src, dest string) error
-	copyFromLocalToS3(src, dest string) error
-}
-
-func executeCp(args []string) {
+	cpCmd.Flags().BoolVar(&globalPreserve, "preserve", false, "keep modification time, permissions and owner of files in object metadata and restore them on download, downloads without them get the object modification time")
+	cpCmd.Flags().BoolVar(&globalNoProgress, "no-progress", false, "do not show live progress, print periodic summaries instead")
+	cpCmd.Flags().StringVar(&globalSourceEndpoint, "source-endpoint", "", "endpoint of the source, for copying between endpoints")
+	cpCmd.Flags().StringVar(&globalDestEndpoint, "dest-endpoint", "", "endpoint of the destination, for copying between endpoints")
+	cpCmd.Flags().StringVar(&globalSSE, "sse", "", "server side encryption of uploaded and copied objects, AES256 or aws:kms")
+	cpCmd.Flags().StringVar(&globalSSEKMSKeyID, "sse-kms-key-id", "", "KMS key id or ARN for aws:kms encryption, default is the AWS managed key")
+	cpCmd.Flags().VarPF(optionalBoolFlag{target: &globalSSEBucketKey, value: true}, "sse-bucket-key", "", "use an S3 bucket key for aws:kms encryption").NoOptDefVal = "true"
+	cpCmd.Flags().VarPF(optionalBoolFlag{target: &globalSSEBucketKey, value: false}, "no-sse-bucket-key", "", "do not use an S3 bucket key for aws:kms encryption").NoOptDefVal = "true"
+	cpCmd.MarkFlagsMutuallyExclusive("sse-bucket-key", "no-sse-bucket-key")
+	cpCmd.Flags().BoolVar(&globalClientEncrypt, "client-encrypt", false, "encrypt uploads before sending them, downloads of encrypted objects are decrypted whenever a key is given")
+	cpCmd.Flags().StringVar(&globalClientKeyFile, "client-key-file", "", "file with 256 bit master key of client side encryption, raw or base64 encoded")
+	cpCmd.Flags().StringVar(&globalPassphraseFile, "passphrase-file", "", "file with passphrase master keys of client side encryption are derived from")
+	cpCmd.MarkFlagsMutuallyExclusive("client-key-file", "passphrase-file")
+	cpCmd.Flags().StringVar(&globalSSECKeyFile, "sse-c-key-file", "", "file with 256 bit SSE-C key, raw or base64 encoded, used for writing and reading objects")
+	cpCmd.Flags().StringVar(&globalContentType, "content-type", "", "content type of uploaded files, detected from extension or content by default")
+	cpCmd.Flags().StringVar(&globalContentEncoding, "content-encoding", "", "content encoding of uploaded files i.e. gzip")
+	cpCmd.Flags().StringVar(&globalCacheControl, "cache-control", "", "cache control header of uploaded files")
+	cpCmd.Flags().StringVar(&globalContentDisposition, "content-disposition", "", "content disposition header of uploaded files")
+	cpCmd.Flags().StringVar(&globalExpires, "expires", "", "expires header of uploaded files, RFC3339 or HTTP date")
+	cpCmd.Flags().StringArrayVar(&globalMetadata, "metadata", nil, "user metadata of uploaded files as key=value, can be repeated")
+	cpCmd.Flags().StringVar(&globalAttributesFile, "attributes-file", "", "YAML file with per pattern content type, headers and metadata of uploaded files")
+	cpCmd.Flags().StringVar(&globalStorageClass, "storage-class", "", "storage class of uploaded and copied objects i.e. GLACIER_IR or DEEP_ARCHIVE")
+	cpCmd.Flags().StringVar(&globalACL, "acl", "", "canned ACL of uploaded and copied objects i.e. bucket-owner-full-control")
+	cpCmd.Flags().StringArrayVar(&globalGrants, "grant", nil, "grant permission of uploaded and copied objects as permission=grantee i.e. read=uri=http://acs.amazonaws.com/groups/global/AllUsers, can be repeated")
+	cpCmd.Flags().StringVar(&globalTagging, "tagging", "", "tags of uploaded and copied objects as k=v&k2=v2, copies keep source tags by default")
+	cpCmd.Flags().StringVar(&globalMetadataDirective, "metadata-directive", "", "COPY keeps headers and metadata of the source in S3 to S3 copies, REPLACE sets them from flags")
+	cpCmd.Flags().StringVar(&globalArchived, "archived", "fail", "what to do with archived objects that are not restored: fail, skip or wait for a restore, which is requested if needed")
+	cpCmd.Flags().Int32Var(&globalRestoreDays, "days", 1, "number of days restored copies are kept, for restores requested with --archived wait")
+	cpCmd.Flags().StringVar(&globalRestoreTier, "tier", "", "retrieval tier of restores requested with --archived wait, Bulk, Standard or Expedited, default is Standard")
+	cpCmd.Flags().BoolVarP(&globalNoClobber, "no-clobber", "n", false, "do not overwrite existing files and objects")
+	cpCmd.Flags().BoolVar(&globalOverwriteIfNewer, "overwrite-if-newer", false, "overwrite existing files and objects only when the source was modified after them")
+	cpCmd.Flags().BoolVar(&globalOverwriteIfDifferent, "overwrite-if-different", false, "overwrite existing files and objects only when their size or contents differ from the source")
+	cpCmd.Flags().StringVar(&globalBackupSuffix, "backup-suffix", "", "keep overwritten files and objects under their name with this suffix i.e. .bak")
+	cpCmd.Flags().StringVar(&globalChecksum, "checksum", "", "store checksums of uploads and verify them on download, crc32, crc32c, sha1 or sha256")
+}
+
+func executeCp(ctx context.Context, args []string) {
 	src, dest := args[0], args[1]
 
-	client, err := newClient()
-	if err != nil {
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "client error: ", err)
-			return
-		}
-	}
-
-	err = executeCopy(client, src, dest)
-
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "copy error: ", err)
-	}
-}
-
-func executeCopy(client s3CopyClient, src, dest string) error {
-	switch {
-	case strings.HasPrefix(src, s3prefix) && strings.HasPrefix(dest, s3prefix):
-		return client.copyFromS3ToS3(src, dest)
-	case strings.HasPrefix(src, s3prefix):
-		return client.copyFromS3ToLocal(src, dest)
-	case strings.HasPrefix(dest, s3prefix):
-		return client.copyFromLocalToS3(src, dest)
-	default:
-		return errors.New("local to local copy is not supported")
-	}
-}
-
-func (s *s3client) copyFromLocalToS3(src, dest string) error {
-	info, err := os.Stat(src)
+	opts, err := newTransferOptions()
 	if err != nil {
-		return err
-	}
-
-	if !info.IsDir() {
-		path, err := s.copySingleToS3(src, dest)
-		if err != nil {
-			return err
-		}
-		fmt.Printf("upload %s %s\n", src, path)
-		return nil
-	}
-
-	fnch := make(chan func() error, globalMaxParallelRequests)
-	outch := make(chan string, globalMaxParallelRequests)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	wg := s.runPooled(cancel, fnch, outch)
-
-	if strings.HasSuffix(dest, "/") {
-		dest += info.Name()
-	} else {
-		dest = dest + "/" + info.Name()
-	}
-
-	dcp := &directoryCopier{
-		dest:          dest,
-		srcRoot:       src,
-		fnch:          fnch,
-		outch:         outch,
-		copyFunc:      s.copySingleToS3,
-		ctx:           ctx,
-		pathSeparator: filepath.Separator,
+		fmt.Fprintln(os.Stderr, "client error: ", err)
+		return
 	}
 
-	err = filepath.WalkDir(src, dcp.walkDirFunc)
-
-	close(fnch)
-	wg.Wait()
-
+	progress := newTransferProgress(os.Stderr, !globalNoProgress && isTerminal(os.Stderr))
+	printer, err := newEventPrinter(globalOutput, progress)
 	if err != nil {
-		return err
+		fmt.Fprintln(os.Stderr, err)
+		return
 	}
 
-	return nil
-}
-
-type directoryCopier struct {
-	dest          string
-	srcRoot       string
-	fnch          chan<- func() error
-	outch         chan<- string
-	ctx           context.Context
-	copyFunc      func(string, string) (string, error)
-	pathSeparator rune
-}
+	opts.Flatten = globalFlatten
+	opts.Preserve = globalPreserve
+	opts.Progress = commandMetrics.wrapProgress(progress, transferDirection(src, dest))
+	opts.OnEvent = printer.print
 
-func (dcp *directoryCopier) walkDirFunc(path string, d fs.DirEntry, err error) error {
+	client, err := newCopyClient(ctx, src, dest, opts)
 	if err != nil {
-		return err
-	}
-
-	if d.IsDir() {
-		return nil
-	}
-
-	remotepath := dcp.generateRemotePath(path)
-
-	select {
-	case <-dcp.ctx.Done():
-		return filepath.SkipAll
-	case dcp.fnch <- func() error {
-		s3path, err := dcp.copyFunc(path, remotepath)
-		if err != nil {
-			return err
-		}
-		dcp.outch <- fmt.Sprintf("upload %s %s", path, s3path)
-		return nil
-	}:
-		return nil
+		fmt.Fprintln(os.Stderr, "client error: ", err)
+		return
 	}
 
-}
+	// aliases are resolved into clients, the transfer engine works with plain s3 paths
+	_, src = splitAlias(src)
+	_, dest = splitAlias(dest)
 
-// generate remote path from local path for upload
-func (dcp *directoryCopier) generateRemotePath(path string) string {
-	subpath := strings.TrimPrefix(path, dcp.srcRoot)
-	if dcp.pathSeparator != '/' {
-		subpath = strings.ReplaceAll(subpath, string(dcp.pathSeparator), "/")
-	}
+	progress.run()
+	start := time.Now()
+	result, err := client.Copy(ctx, src, dest)
+	progress.stop()
 
-	if strings.HasPrefix(subpath, "/") {
-		return dcp.dest + subpath
-	} else {
-		return dcp.dest + "/" + subpath
+	if err != nil && !printer.reported(err) {
+		fmt.Fprintln(os.Stderr, "copy error: ", err)
 	}
+	reportRun(ctx, os.Stderr, "cp", result, start, err)
 }
 
-func (s *s3client) copySingleToS3(src, dest string) (string, error) {
-	bucket, key, err := extractBucketAndKey(dest)
-	if err != nil {
-		return "", err
-	}
+// create client for the s3 side of the copy, with a separate destination client when
+// source and destination are on different endpoints
+func newCopyClient(ctx context.Context, src, dest string, opts s3transfer.Options) (*s3transfer.Client, error) {
+	srcAlias, _ := splitAlias(src)
+	destAlias, _ := splitAlias(dest)
 
-	f, err := os.Open(src)
-	if err != nil {
-		return "", err
+	srcOptions, destOptions := globalClientOptions, globalClientOptions
+	if len(globalSourceEndpoint) != 0 {
+		srcOptions.endpoint = globalSourceEndpoint
 	}
-	defer f.Close()
-
-	if strings.HasSuffix(key, "/") {
-		key += f.Name()
-	}
-
-	_, err = s.client.PutObject(context.Background(), &s3.PutObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-		Body:   f,
-	})
-
-	if err != nil {
-		return "", err
+	if len(globalDestEndpoint) != 0 {
+		destOptions.endpoint = globalDestEndpoint
 	}
 
-	return generateS3Path(bucket, key), nil
-}
-
-func (s *s3client) copyFromS3ToLocal(src, dest string) error {
-	if !strings.HasSuffix(src, "/") && !strings.HasSuffix(src, "*") {
-		path, err := s.copySingleFromS3ToLocal(src, dest)
+	if !isS3Path(src) {
+		api, err := newS3API(ctx, destOptions, destAlias)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		fmt.Printf("Download %s to %s\n", src, path)
-		return nil
-	}
-
-	src = strings.TrimSuffix(src, "*")
-
-	bucket, prefix, err := extractBucketAndKey(src)
-	if err != nil {
-		return err
+		client := s3transfer.New(api, opts)
+		commandMetrics.addClient(client)
+		return client, nil
 	}
 
-	fnch := make(chan func() error, globalMaxParallelRequests)
-	outch := make(chan string, globalMaxParallelRequests)
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	wg := s.runPooled(cancel, fnch, outch)
-
-	lsParams := listParams{bucket: bucket, prefix: aws.String(prefix)}
-	s.listObject(ctx, lsParams, func(output *s3.ListObjectsV2Output) {
-	loop:
-		for _, o := range output.Contents {
-			select {
-			case <-ctx.Done():
-				break loop
-			default:
-				s.enqueuForDownload(ctx, bucket, o, src, prefix, dest, fnch, outch)
-			}
-		}
-	})
-
-	close(fnch)
-	wg.Wait()
-
+	api, err := newS3API(ctx, srcOptions, srcAlias)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
-}
-
-func (s *s3client) enqueuForDownload(ctx context.Context, bucket string, o types.Object, src, prefix, dest string, fnch chan func() error, outch chan string) {
-	select {
-	case <-ctx.Done():
-		return
-	case fnch <- func() error {
-		if globalFlatten {
-			path, err := s.downloadFile(bucket, aws.ToString(o.Key), dest)
-			if err != nil {
-				return err
-			}
-			outch <- fmt.Sprintf("Download %s to %s", src, path)
-			return nil
-		}
-
-		path := convertToLocalPath(prefix, aws.ToString(o.Key), dest)
-		err := os.MkdirAll(filepath.Dir(path), 0755)
-		if err != nil {
-			return err
-		}
-
-		path, err = s.downloadFile(bucket, aws.ToString(o.Key), path)
+	if isS3Path(dest) && (srcAlias != destAlias || srcOptions.endpoint != destOptions.endpoint) {
+		opts.Destination, err = newS3API(ctx, destOptions, destAlias)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		srcPath := src + "/" + aws.ToString(o.Key)
-		outch <- fmt.Sprintf("Download %s to %s", srcPath, path)
-		return nil
-	}:
-		// noop
-	}
-}
-
-// convert aws key excluding prefix to local path under dest as file stored subfolders
-func convertToLocalPath(prefix, key, dest string) string {
-	p := strings.TrimPrefix(key, prefix)
-	cols := []string{dest}
-	cols = append(cols, strings.Split(p, "/")...)
-	return filepath.Join(cols...)
-}
-
-func (s *s3client) copySingleFromS3ToLocal(src, dest string) (string, error) {
-	bucket, key, err := extractBucketAndKey(src)
-	if err != nil {
-		return "", err
-	}
-
-	return s.downloadFile(bucket, key, dest)
-}
-
-func (s *s3client) downloadFile(bucket string, key string, dest string) (string, error) {
-	output, err := s.client.GetObject(context.Background(), &s3.GetObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(key),
-	})
-
-	if err != nil {
-		return "", err
-	}
-
-	defer output.Body.Close()
-
-	isdir, err := isDirectory(dest)
-	if err != nil {
-		return "", err
-	}
-
-	if isdir {
-		dest = filepath.Join(dest, extractS3FileName(key))
-	}
-
-	f, err := os.Create(dest)
-	if err != nil {
-		return "", err
 	}
-	defer f.Close()
 
-	io.Copy(f, output.Body)
-	return dest, nil
+	client := s3transfer.New(api, opts)
+	commandMetrics.addClient(client)
+	return client, nil
 }
 
-func (s *s3client) copyFromS3ToS3(src, dest string) error {
-	// TODO implement
-	fmt.Fprintln(os.Stderr, "not implemented yet")
-	return nil
-}
-
-var errDirectoryNotExists = errors.New("directory does not exist")
-
-func isDirectory(name string) (bool, error) {
-	info, err := os.Stat(name)
-	if err == nil {
-		return info.IsDir(), nil
-	}
-
-	if os.IsNotExist(err) && strings.HasSuffix(name, string(filepath.Separator)) {
-		return false, errDirectoryNotExists
-	}
-
-	if os.IsNotExist(err) {
-		return false, nil
-	}
-
-	return false, err
-}
-
-func extractS3FileName(s3key string) string {
-	idx := strings.LastIndexByte(s3key, '/')
-	if idx == -1 {
-		return s3key
+// direction of bytes transferred by copying src to dest
+func transferDirection(src, dest string) string {
+	switch {
+	case isS3Path(src) && isS3Path(dest):
+		return directionCopy
+	case isS3Path(src):
+		return directionDownload
+	default:
+		return directionUpload
 	}
-
-	return s3key[idx+1:]
 }
@@ -0,0 +1,47 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+var globalClientEncrypt bool
+var globalClientKeyFile string
+var globalPassphraseFile string
+
+// client side encryption from flags, nil when no key is given
+func newClientEncryption() (*s3transfer.ClientEncryption, error) {
+	var ce *s3transfer.ClientEncryption
+	switch {
+	case len(globalClientKeyFile) != 0:
+		key, err := readKeyFile(globalClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		if ce, err = s3transfer.NewClientEncryption(key); err != nil {
+			return nil, err
+		}
+	case len(globalPassphraseFile) != 0:
+		b, err := os.ReadFile(globalPassphraseFile)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read passphrase %w", err)
+		}
+		if ce, err = s3transfer.NewPassphraseEncryption(strings.TrimRight(string(b), "\r\n")); err != nil {
+			return nil, err
+		}
+	case globalClientEncrypt:
+		return nil, errors.New("client-encrypt requires client-key-file or passphrase-file")
+	default:
+		return nil, nil
+	}
+
+	ce.EncryptUploads = globalClientEncrypt
+	return ce, nil
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClientEncryption(t *testing.T) {
+	dir := t.TempDir()
+	keyFile, passphraseFile := filepath.Join(dir, "key"), filepath.Join(dir, "passphrase")
+	require.NoError(t, os.WriteFile(keyFile, bytes.Repeat([]byte{1}, 32), 0600))
+	require.NoError(t, os.WriteFile(passphraseFile, []byte("secret\n"), 0600))
+
+	cases := []struct {
+		name            string
+		inputEncrypt    bool
+		inputKey        string
+		inputPassphrase string
+		wantNil         bool
+		wantErr         bool
+	}{
+		{"disabled", false, "", "", true, false},
+		{"key", true, keyFile, "", false, false},
+		{"passphrase for decrypting", false, "", passphraseFile, false, false},
+		{"encrypt without key", true, "", "", true, true},
+		{"missing key file", true, filepath.Join(dir, "missing"), "", true, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			globalClientEncrypt, globalClientKeyFile, globalPassphraseFile = c.inputEncrypt, c.inputKey, c.inputPassphrase
+			defer func() {
+				globalClientEncrypt, globalClientKeyFile, globalPassphraseFile = false, "", ""
+			}()
+
+			got, err := newClientEncryption()
+			if (err != nil) != c.wantErr {
+				t.Errorf("got %v want error %v", err, c.wantErr)
+			}
+			if (got == nil) != c.wantNil {
+				t.Errorf("got %v want nil %v", got, c.wantNil)
+			}
+			if got != nil && got.EncryptUploads != c.inputEncrypt {
+				t.Errorf("got %v want %v", got.EncryptUploads, c.inputEncrypt)
+			}
+		})
+	}
+}
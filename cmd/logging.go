@@ -0,0 +1,132 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/logging"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+var globalVerbose bool
+var globalDebug bool
+var globalLogFormat string
+
+// level of logs enabled by verbosity flags, warnings and errors are always logged
+func logLevel() slog.Level {
+	switch {
+	case globalDebug || globalClientOptions.logWire:
+		return slog.LevelDebug
+	case globalVerbose:
+		return slog.LevelInfo
+	default:
+		return slog.LevelWarn
+	}
+}
+
+func newLogger(w io.Writer, format string, level slog.Level) (*slog.Logger, error) {
+	opts := &slog.HandlerOptions{Level: level}
+	switch format {
+	case "text":
+		return slog.New(slog.NewTextHandler(w, opts)), nil
+	case "json":
+		return slog.New(slog.NewJSONHandler(w, opts)), nil
+	default:
+		return nil, fmt.Errorf("log-format: unknown format %q, should be text or json", format)
+	}
+}
+
+// sdkLogger forwards logs of the SDK, including wire logs enabled with --log-wire, to slog
+type sdkLogger struct {
+	logger *slog.Logger
+}
+
+func (l sdkLogger) Logf(classification logging.Classification, format string, v ...interface{}) {
+	level := slog.LevelDebug
+	if classification == logging.Warn {
+		level = slog.LevelWarn
+	}
+	l.logger.Log(context.Background(), level, redactHeaders(fmt.Sprintf(format, v...)), "source", "sdk")
+}
+
+// headers of dumped requests carrying credentials or SSE-C keys
+var secretHeaderPattern = regexp.MustCompile(`(?im)^((?:authorization|x-amz-security-token|x-amz-server-side-encryption-customer-key|x-amz-copy-source-server-side-encryption-customer-key):[ \t]*)[^\r\n]*`)
+
+// replace values of secret headers in wire logs, the SDK dumps requests as they are sent
+func redactHeaders(msg string) string {
+	return secretHeaderPattern.ReplaceAllString(msg, "${1}REDACTED")
+}
+
+type attemptsKey struct{}
+
+// requestLogger logs S3 operations and every attempt of their HTTP requests. Failures and retries are
+// logged at info level, successful requests at debug level.
+type requestLogger struct {
+	logger *slog.Logger
+}
+
+func (rl requestLogger) addMiddleware(stack *middleware.Stack) error {
+	err := stack.Initialize.Add(middleware.InitializeMiddlewareFunc("OperationLogging",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			attempts := new(int)
+			ctx = middleware.WithStackValue(ctx, attemptsKey{}, attempts)
+
+			start := time.Now()
+			out, metadata, err := next.HandleInitialize(ctx, in)
+
+			attrs := []any{"operation", awsmiddleware.GetOperationName(ctx), "attempts", *attempts, "duration", time.Since(start)}
+			if err != nil {
+				rl.logger.Info("s3 operation failed", append(attrs, "error", err)...)
+			} else {
+				rl.logger.Debug("s3 operation", attrs...)
+			}
+			return out, metadata, err
+		}), middleware.After)
+	if err != nil {
+		return err
+	}
+
+	// after retry middleware, so that each attempt is logged
+	return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("AttemptLogging",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			attempt := 1
+			if attempts, ok := middleware.GetStackValue(ctx, attemptsKey{}).(*int); ok {
+				*attempts++
+				attempt = *attempts
+			}
+
+			start := time.Now()
+			out, metadata, err := next.HandleFinalize(ctx, in)
+
+			attrs := []any{"operation", awsmiddleware.GetOperationName(ctx), "attempt", attempt}
+			if req, ok := in.Request.(*smithyhttp.Request); ok {
+				attrs = append(attrs, "method", req.Method, "url", req.URL.String())
+			}
+			if resp, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok {
+				attrs = append(attrs, "status", resp.StatusCode)
+			}
+			if id, ok := awsmiddleware.GetRequestIDMetadata(metadata); ok {
+				attrs = append(attrs, "request_id", id)
+			}
+			attrs = append(attrs, "duration", time.Since(start))
+			if err != nil {
+				attrs = append(attrs, "error", err)
+			}
+
+			if attempt > 1 {
+				rl.logger.Info("s3 request retried", attrs...)
+			} else {
+				rl.logger.Debug("s3 request", attrs...)
+			}
+			return out, metadata, err
+		}), "Retry", middleware.After)
+}
@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogLevel(t *testing.T) {
+	cases := []struct {
+		name         string
+		inputVerbose bool
+		inputDebug   bool
+		inputWire    bool
+		want         slog.Level
+	}{
+		{"default", false, false, false, slog.LevelWarn},
+		{"verbose", true, false, false, slog.LevelInfo},
+		{"debug", true, true, false, slog.LevelDebug},
+		{"wire logging", false, false, true, slog.LevelDebug},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			globalVerbose, globalDebug, globalClientOptions.logWire = c.inputVerbose, c.inputDebug, c.inputWire
+			defer func() {
+				globalVerbose, globalDebug, globalClientOptions.logWire = false, false, false
+			}()
+
+			got := logLevel()
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestNewLoggerJSON(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "json", slog.LevelInfo)
+	require.NoError(t, err)
+
+	logger.Debug("hidden")
+	logger.Info("shown", "operation", "GetObject")
+
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, "shown", record["msg"])
+	require.Equal(t, "GetObject", record["operation"])
+}
+
+func TestNewLoggerForError(t *testing.T) {
+	_, err := newLogger(io.Discard, "xml", slog.LevelInfo)
+	require.Error(t, err)
+}
+
+// httpClient that responds with given status codes in order
+type statusClient struct {
+	statuses []int
+}
+
+func (c *statusClient) Do(req *http.Request) (*http.Response, error) {
+	status := c.statuses[0]
+	c.statuses = c.statuses[1:]
+	return &http.Response{
+		StatusCode: status,
+		Header:     http.Header{"X-Amz-Request-Id": []string{"req-1"}},
+		Body:       io.NopCloser(strings.NewReader("")),
+		Request:    req,
+	}, nil
+}
+
+func TestRequestLoggerMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "json", slog.LevelDebug)
+	require.NoError(t, err)
+
+	client := s3.New(s3.Options{
+		Region:      "eu-west-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient:  &statusClient{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}},
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		}),
+		APIOptions: []func(*middleware.Stack) error{requestLogger{logger: logger}.addMiddleware},
+	})
+
+	_, err = client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String("mybucket"),
+		Key:    aws.String("my/key"),
+	})
+	require.NoError(t, err)
+
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record map[string]any
+		require.NoError(t, json.Unmarshal([]byte(line), &record))
+		records = append(records, record)
+	}
+
+	require.Len(t, records, 3)
+	require.Equal(t, "s3 request", records[0]["msg"])
+	require.Equal(t, float64(http.StatusServiceUnavailable), records[0]["status"])
+	require.Equal(t, "s3 request retried", records[1]["msg"])
+	require.Equal(t, "req-1", records[1]["request_id"])
+	require.Equal(t, "s3 operation", records[2]["msg"])
+	require.Equal(t, "HeadObject", records[2]["operation"])
+	require.Equal(t, float64(2), records[2]["attempts"])
+}
+
+func TestSDKLoggerRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := newLogger(&buf, "text", slog.LevelDebug)
+	require.NoError(t, err)
+
+	key := strings.Repeat("k", 32)
+	client := s3.New(s3.Options{
+		Region:        "eu-west-1",
+		Credentials:   credentials.NewStaticCredentialsProvider("AKID", "secret", "session-token"),
+		HTTPClient:    &statusClient{statuses: []int{http.StatusOK}},
+		ClientLogMode: aws.LogRequest,
+		Logger:        sdkLogger{logger: logger},
+	})
+
+	_, err = client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket:               aws.String("mybucket"),
+		Key:                  aws.String("my/key"),
+		SSECustomerAlgorithm: aws.String("AES256"),
+		SSECustomerKey:       aws.String(base64.StdEncoding.EncodeToString([]byte(key))),
+	})
+	require.NoError(t, err)
+
+	logs := buf.String()
+	require.Contains(t, logs, "REDACTED")
+	for _, secret := range []string{base64.StdEncoding.EncodeToString([]byte(key)), "session-token", "Signature="} {
+		require.NotContains(t, logs, secret)
+	}
+	require.Contains(t, logs, "X-Amz-Server-Side-Encryption-Customer-Algorithm: AES256")
+}
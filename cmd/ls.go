@@ -6,12 +6,12 @@ package cmd
 import (
 	"context"
 	"fmt"
-	"log/slog"
 	"os"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
 	"github.com/spf13/cobra"
 )
 
@@ -21,49 +21,72 @@ var lsCmd = &cobra.Command{
 	Short: "List S3",
 
 	Run: func(cmd *cobra.Command, args []string) {
-		executeLs(args)
+		executeLs(cmd.Context(), args)
 	},
 }
 
+var globalTagFilters []string
+
 func init() {
 	rootCmd.AddCommand(lsCmd)
+	lsCmd.Flags().StringVar(&globalSSECKeyFile, "sse-c-key-file", "", "file with 256 bit SSE-C key, raw or base64 encoded, for reading details of SSE-C objects")
+	lsCmd.Flags().StringArrayVar(&globalTagFilters, "tag", nil, "list only objects with tag key=value, or key with any value, can be repeated. Tags are fetched for each listed object")
 }
 
-type listParams struct {
-	bucket    string
-	prefix    *string
-	delimiter *string
+// parse repeated tag filter flags
+func parseTagFilters(values []string) ([]s3transfer.TagFilter, error) {
+	filters := make([]s3transfer.TagFilter, 0, len(values))
+	for _, v := range values {
+		k, val, ok := strings.Cut(v, "=")
+		if len(k) == 0 {
+			return nil, fmt.Errorf("tag filter should be key=value or key, got %q", v)
+		}
+		filters = append(filters, s3transfer.TagFilter{Key: k, Value: val, AnyValue: !ok})
+	}
+	return filters, nil
 }
 
-func executeLs(args []string) {
-	client, err := newClient()
+func executeLs(ctx context.Context, args []string) {
+	path := args[0]
+	alias, _ := splitAlias(path)
+
+	opts, err := newTransferOptions()
 	if err != nil {
-		if err != nil {
-			fmt.Fprintln(os.Stderr, "client error: ", err)
-			return
-		}
+		fmt.Fprintln(os.Stderr, "client error: ", err)
+		return
+	}
+
+	client, err := newClient(ctx, alias, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "client error: ", err)
+		return
 	}
 
-	path := args[0]
 	bucket, key, err := extractBucketAndKey(path)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "error: ", err)
 		return
 	}
 
+	tags, err := parseTagFilters(globalTagFilters)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error: ", err)
+		return
+	}
+
 	switch {
 	case strings.HasSuffix(key, "*"):
 		key = strings.TrimSuffix(key, "*")
-		params := listParams{bucket: bucket, prefix: aws.String(key)}
-		err = client.listObject(context.Background(), params, printObjectDetails)
+		params := s3transfer.ListParams{Bucket: bucket, Prefix: key, Tags: tags}
+		err = client.List(ctx, params, printObjectDetails)
 	case strings.HasSuffix(key, "/"):
-		params := listParams{bucket: bucket, prefix: aws.String(key), delimiter: aws.String("/")}
-		err = client.listObject(context.Background(), params, printObjectDetails)
+		params := s3transfer.ListParams{Bucket: bucket, Prefix: key, Delimiter: "/", Tags: tags}
+		err = client.List(ctx, params, printObjectDetails)
 	case len(key) == 0:
-		params := listParams{bucket: bucket, delimiter: aws.String("/")}
-		err = client.listObject(context.Background(), params, printObjectDetails)
+		params := s3transfer.ListParams{Bucket: bucket, Delimiter: "/", Tags: tags}
+		err = client.List(ctx, params, printObjectDetails)
 	default:
-		err = client.listSingleObject(bucket, key)
+		err = listSingleObject(ctx, client, bucket, key, tags)
 	}
 
 	if err != nil {
@@ -84,54 +107,23 @@ func printObjectDetails(output *s3.ListObjectsV2Output) {
 	}
 }
 
-func (s *s3client) listSingleObject(bucket, path string) error {
-	output, err := s.client.HeadObject(context.Background(), &s3.HeadObjectInput{
-		Bucket: aws.String(bucket),
-		Key:    aws.String(path),
-	})
-
+func listSingleObject(ctx context.Context, client *s3transfer.Client, bucket, path string, tags []s3transfer.TagFilter) error {
+	output, err := client.Head(ctx, bucket, path)
 	if err != nil {
 		return err
 	}
 
-	// todo format time and size
-	fmt.Printf("%s\t%d\t%s\n", output.LastModified, aws.ToInt64(output.ContentLength), path)
-	return nil
-}
-
-func (s *s3client) listObject(ctx context.Context, params listParams, onList func(*s3.ListObjectsV2Output)) error {
-	isTruncated := true
-	var continuationToken *string
-
-loop:
-	for isTruncated {
-		select {
-		case <-ctx.Done():
-			break loop
-		default:
-			// do nothing
-		}
-
-		output, err := s.client.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
-			Bucket:            aws.String(params.bucket),
-			Prefix:            params.prefix,
-			Delimiter:         params.delimiter,
-			ContinuationToken: continuationToken,
-		})
-
+	if len(tags) != 0 {
+		objectTags, err := client.Tags(ctx, []string{s3transfer.S3Path(bucket, path)})
 		if err != nil {
 			return err
 		}
-
-		if *output.IsTruncated {
-			slog.Debug("s3 list pagination", "token", aws.ToString(output.NextContinuationToken), "isTruncated", *output.IsTruncated)
+		if !s3transfer.MatchesTags(objectTags[0].Tags, tags) {
+			return nil
 		}
-
-		onList(output)
-
-		isTruncated = *output.IsTruncated
-		continuationToken = output.NextContinuationToken
 	}
 
+	// todo format time and size
+	fmt.Printf("%s\t%d\t%s\n", output.LastModified, aws.ToInt64(output.ContentLength), path)
 	return nil
 }
@@ -0,0 +1,287 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+var globalMetricsAddr string
+var globalMetricsFile string
+
+// metrics of the running command, nil unless --metrics-addr or --metrics-file is given
+var commandMetrics *metrics
+
+// upper bounds of request duration histogram buckets in seconds, requests with large bodies take minutes
+var requestDurationBuckets = []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300}
+
+// directions of transferred bytes, copy is used for copies between s3 paths
+const (
+	directionUpload   = "upload"
+	directionDownload = "download"
+	directionCopy     = "copy"
+)
+
+type requestKey struct {
+	operation string
+	status    string
+}
+
+type histogram struct {
+	// non-cumulative counts of observations per bucket, the last one is +Inf
+	buckets []int64
+	sum     float64
+	count   int64
+}
+
+func (h *histogram) observe(v float64) {
+	i := sort.SearchFloat64s(requestDurationBuckets, v)
+	h.buckets[i]++
+	h.sum += v
+	h.count++
+}
+
+// metrics are exposed in Prometheus text format
+type metrics struct {
+	mu        sync.Mutex
+	requests  map[requestKey]int64
+	durations map[string]*histogram
+	clients   []*s3transfer.Client
+
+	bytes   map[string]*atomic.Int64
+	retries *retryCounter
+}
+
+func newMetrics(retries *retryCounter) *metrics {
+	return &metrics{
+		requests:  make(map[requestKey]int64),
+		durations: make(map[string]*histogram),
+		bytes: map[string]*atomic.Int64{
+			directionUpload:   new(atomic.Int64),
+			directionDownload: new(atomic.Int64),
+			directionCopy:     new(atomic.Int64),
+		},
+		retries: retries,
+	}
+}
+
+// start metrics endpoint if --metrics-addr is given, metrics are collected for --metrics-file too
+func startMetrics() error {
+	if len(globalMetricsAddr) == 0 && len(globalMetricsFile) == 0 {
+		return nil
+	}
+
+	commandMetrics = newMetrics(requestRetries)
+	if len(globalMetricsAddr) == 0 {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", globalMetricsAddr)
+	if err != nil {
+		return fmt.Errorf("metrics-addr: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", commandMetrics)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(ln); err != nil {
+			slog.Warn("metrics endpoint stopped", "error", err)
+		}
+	}()
+	return nil
+}
+
+// write final metrics to --metrics-file
+func writeMetricsFile() {
+	if commandMetrics == nil || len(globalMetricsFile) == 0 {
+		return
+	}
+
+	f, err := os.Create(globalMetricsFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot write metrics", err)
+		return
+	}
+	defer f.Close()
+
+	if err := commandMetrics.write(f); err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot write metrics", err)
+	}
+}
+
+// addMiddleware records every attempt of requests, after retry middleware
+func (m *metrics) addMiddleware(stack *middleware.Stack) error {
+	return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("AttemptMetrics",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			start := time.Now()
+			out, metadata, err := next.HandleFinalize(ctx, in)
+
+			// no response, or one with status 0, for connection errors
+			status := "error"
+			if resp, ok := awsmiddleware.GetRawResponse(metadata).(*smithyhttp.Response); ok && resp.StatusCode != 0 {
+				status = strconv.Itoa(resp.StatusCode)
+			}
+			m.observeRequest(awsmiddleware.GetOperationName(ctx), status, time.Since(start))
+			return out, metadata, err
+		}), "Retry", middleware.After)
+}
+
+func (m *metrics) observeRequest(operation, status string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.requests[requestKey{operation: operation, status: status}]++
+	h, ok := m.durations[operation]
+	if !ok {
+		h = &histogram{buckets: make([]int64, len(requestDurationBuckets)+1)}
+		m.durations[operation] = h
+	}
+	h.observe(duration.Seconds())
+}
+
+// addClient registers client for worker pool gauges, nil safe
+func (m *metrics) addClient(client *s3transfer.Client) {
+	if m == nil {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.clients = append(m.clients, client)
+}
+
+// wrapProgress counts bytes reported to progress in the given direction, nil safe
+func (m *metrics) wrapProgress(progress s3transfer.Progress, direction string) s3transfer.Progress {
+	if m == nil {
+		return progress
+	}
+	return metricsProgress{Progress: progress, bytes: m.bytes[direction]}
+}
+
+type metricsProgress struct {
+	s3transfer.Progress
+	bytes *atomic.Int64
+}
+
+func (p metricsProgress) StartFile(name string, size int64) s3transfer.FileProgress {
+	return &metricsFileProgress{FileProgress: p.Progress.StartFile(name, size), bytes: p.bytes}
+}
+
+// metricsFileProgress counts bytes of a file once, bytes read again after a body is rewound are not counted
+type metricsFileProgress struct {
+	s3transfer.FileProgress
+	bytes   *atomic.Int64
+	done    int64
+	counted int64
+}
+
+func (fp *metricsFileProgress) AddBytes(n int64) {
+	fp.FileProgress.AddBytes(n)
+	fp.done += n
+	if fp.done > fp.counted {
+		fp.bytes.Add(fp.done - fp.counted)
+		fp.counted = fp.done
+	}
+}
+
+func (m *metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	if err := m.write(w); err != nil {
+		slog.Warn("cannot write metrics", "error", err)
+	}
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// write metrics in Prometheus text exposition format
+func (m *metrics) write(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "# HELP s3cli_requests_total S3 request attempts by operation and HTTP status, error when no response was received.")
+	fmt.Fprintln(bw, "# TYPE s3cli_requests_total counter")
+	keys := make([]requestKey, 0, len(m.requests))
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].operation != keys[j].operation {
+			return keys[i].operation < keys[j].operation
+		}
+		return keys[i].status < keys[j].status
+	})
+	for _, k := range keys {
+		fmt.Fprintf(bw, "s3cli_requests_total{operation=\"%s\",status=\"%s\"} %d\n",
+			labelEscaper.Replace(k.operation), labelEscaper.Replace(k.status), m.requests[k])
+	}
+
+	fmt.Fprintln(bw, "# HELP s3cli_request_duration_seconds Duration of S3 request attempts by operation.")
+	fmt.Fprintln(bw, "# TYPE s3cli_request_duration_seconds histogram")
+	operations := make([]string, 0, len(m.durations))
+	for op := range m.durations {
+		operations = append(operations, op)
+	}
+	sort.Strings(operations)
+	for _, op := range operations {
+		h := m.durations[op]
+		label := labelEscaper.Replace(op)
+		var cumulative int64
+		for i, count := range h.buckets {
+			cumulative += count
+			le := "+Inf"
+			if i < len(requestDurationBuckets) {
+				le = strconv.FormatFloat(requestDurationBuckets[i], 'g', -1, 64)
+			}
+			fmt.Fprintf(bw, "s3cli_request_duration_seconds_bucket{operation=\"%s\",le=\"%s\"} %d\n", label, le, cumulative)
+		}
+		fmt.Fprintf(bw, "s3cli_request_duration_seconds_sum{operation=\"%s\"} %s\n", label, strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(bw, "s3cli_request_duration_seconds_count{operation=\"%s\"} %d\n", label, h.count)
+	}
+
+	fmt.Fprintln(bw, "# HELP s3cli_request_retries_total Retried S3 request attempts.")
+	fmt.Fprintln(bw, "# TYPE s3cli_request_retries_total counter")
+	fmt.Fprintf(bw, "s3cli_request_retries_total %d\n", m.retries.count.Load())
+
+	fmt.Fprintln(bw, "# HELP s3cli_transferred_bytes_total Bytes transferred by direction.")
+	fmt.Fprintln(bw, "# TYPE s3cli_transferred_bytes_total counter")
+	for _, direction := range []string{directionUpload, directionDownload, directionCopy} {
+		fmt.Fprintf(bw, "s3cli_transferred_bytes_total{direction=\"%s\"} %d\n", direction, m.bytes[direction].Load())
+	}
+
+	var pools s3transfer.PoolStats
+	for _, c := range m.clients {
+		stats := c.PoolStats()
+		pools.InFlight += stats.InFlight
+		pools.Queued += stats.Queued
+	}
+	fmt.Fprintln(bw, "# HELP s3cli_workers_in_flight Workers running a transfer or delete.")
+	fmt.Fprintln(bw, "# TYPE s3cli_workers_in_flight gauge")
+	fmt.Fprintf(bw, "s3cli_workers_in_flight %d\n", pools.InFlight)
+	fmt.Fprintln(bw, "# HELP s3cli_queue_depth Transfers or deletes queued for the workers.")
+	fmt.Fprintln(bw, "# TYPE s3cli_queue_depth gauge")
+	fmt.Fprintf(bw, "s3cli_queue_depth %d\n", pools.Queued)
+
+	return bw.Flush()
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsMiddleware(t *testing.T) {
+	retries := &retryCounter{}
+	m := newMetrics(retries)
+	client := s3.New(s3.Options{
+		Region:      "eu-west-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient:  &statusClient{statuses: []int{http.StatusServiceUnavailable, http.StatusOK}},
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		}),
+		APIOptions: []func(*middleware.Stack) error{retries.addMiddleware, m.addMiddleware},
+	})
+
+	_, err := client.HeadObject(context.Background(), &s3.HeadObjectInput{
+		Bucket: aws.String("mybucket"),
+		Key:    aws.String("my/key"),
+	})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	got := rec.Body.String()
+
+	for _, want := range []string{
+		`s3cli_requests_total{operation="HeadObject",status="200"} 1`,
+		`s3cli_requests_total{operation="HeadObject",status="503"} 1`,
+		`s3cli_request_duration_seconds_bucket{operation="HeadObject",le="+Inf"} 2`,
+		`s3cli_request_duration_seconds_count{operation="HeadObject"} 2`,
+		`s3cli_request_retries_total 1`,
+		`s3cli_workers_in_flight 0`,
+	} {
+		require.Contains(t, got, want+"\n")
+	}
+}
+
+func TestMetricsProgressCountsRewoundBytesOnce(t *testing.T) {
+	m := newMetrics(&retryCounter{})
+	progress := m.wrapProgress(newTransferProgress(&bytes.Buffer{}, false), directionUpload)
+
+	fp := progress.StartFile("file", 100)
+	fp.AddBytes(100)
+	// body read for checksum and rewound
+	fp.AddBytes(-100)
+	fp.AddBytes(60)
+	fp.AddBytes(40)
+	fp.Finish()
+
+	require.Equal(t, int64(100), m.bytes[directionUpload].Load())
+	require.Equal(t, int64(0), m.bytes[directionDownload].Load())
+}
+
+func TestTransferDirection(t *testing.T) {
+	cases := []struct {
+		name      string
+		inputSrc  string
+		inputDest string
+		want      string
+	}{
+		{"upload", "data/", "s3://bucket/", directionUpload},
+		{"download", "s3://bucket/key", "data/", directionDownload},
+		{"copy", "s3://bucket/key", "s3://other/key", directionCopy},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := transferDirection(c.inputSrc, c.inputDest)
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+var globalOutput string
+
+// eventPrinter prints events of cp, rm, restore and tag, either as lines of text or one JSON object per line
+type eventPrinter struct {
+	json     bool
+	progress *transferProgress
+	out      io.Writer
+	errOut   io.Writer
+	// errors of failed events, so that they are not printed again when the run returns them
+	failures []error
+}
+
+func newEventPrinter(format string, progress *transferProgress) (*eventPrinter, error) {
+	if format != "text" && format != "json" {
+		return nil, fmt.Errorf("output: unknown format %q, should be text or json", format)
+	}
+	return &eventPrinter{json: format == "json", progress: progress, out: os.Stdout, errOut: os.Stderr}, nil
+}
+
+// eventRecord is the JSON representation of an event
+type eventRecord struct {
+	Time            time.Time `json:"time"`
+	Operation       string    `json:"operation"`
+	Source          string    `json:"source"`
+	Destination     string    `json:"destination,omitempty"`
+	Bytes           int64     `json:"bytes"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	Status          string    `json:"status"`
+	Error           string    `json:"error,omitempty"`
+	ErrorCode       string    `json:"error_code,omitempty"`
+}
+
+func (p *eventPrinter) print(e s3transfer.Event) {
+	if e.Status == s3transfer.StatusFailed && (len(p.failures) == 0 || !errors.Is(e.Err, p.failures[len(p.failures)-1])) {
+		p.failures = append(p.failures, e.Err)
+	}
+
+	if p.json {
+		p.progress.fprintln(p.out, string(newEventRecord(e)))
+		return
+	}
+
+	if e.Status == s3transfer.StatusSkipped {
+		p.progress.fprintln(p.errOut, fmt.Sprintf("Skipping %s: %v", e.Source, errors.Unwrap(e.Err)))
+		return
+	}
+
+	if e.Err != nil {
+		p.progress.fprintln(p.errOut, failureText(e))
+		return
+	}
+
+	switch e.Op {
+	case s3transfer.OpDelete:
+		p.progress.fprintln(p.out, fmt.Sprintf("%s deleted", e.Source))
+	case s3transfer.OpUpload:
+		p.progress.fprintln(p.out, fmt.Sprintf("upload %s to %s", e.Source, e.Destination))
+	case s3transfer.OpDownload:
+		p.progress.fprintln(p.out, fmt.Sprintf("download %s to %s", e.Source, e.Destination))
+	case s3transfer.OpCopy:
+		p.progress.fprintln(p.out, fmt.Sprintf("copy %s to %s", e.Source, e.Destination))
+	case s3transfer.OpRestore:
+		p.progress.fprintln(p.out, fmt.Sprintf("restore requested for %s", e.Source))
+	case s3transfer.OpTag:
+		p.progress.fprintln(p.out, fmt.Sprintf("%s tagged", e.Source))
+	}
+}
+
+func newEventRecord(e s3transfer.Event) []byte {
+	record := eventRecord{
+		Time:            time.Now().UTC(),
+		Operation:       string(e.Op),
+		Source:          e.Source,
+		Destination:     e.Destination,
+		Bytes:           e.Bytes,
+		DurationSeconds: e.Duration.Seconds(),
+		Status:          string(e.Status),
+	}
+
+	if e.Err != nil {
+		record.Error = e.Err.Error()
+		var deleteErr *s3transfer.DeleteError
+		if errors.As(e.Err, &deleteErr) {
+			record.ErrorCode = deleteErr.Code
+		}
+	}
+
+	// record has no values that cannot be marshalled
+	b, _ := json.Marshal(record)
+	return b
+}
+
+func failureText(e s3transfer.Event) string {
+	switch e.Op {
+	case s3transfer.OpDelete:
+		return fmt.Sprintf("Error while deleting %s: %v", e.Source, e.Err)
+	case s3transfer.OpUpload:
+		return fmt.Sprintf("Error while uploading %s to %s: %v", e.Source, e.Destination, e.Err)
+	case s3transfer.OpDownload:
+		return fmt.Sprintf("Error while downloading %s to %s: %v", e.Source, e.Destination, e.Err)
+	case s3transfer.OpRestore:
+		return fmt.Sprintf("Error while restoring %s: %v", e.Source, e.Err)
+	case s3transfer.OpTag:
+		return fmt.Sprintf("Error while tagging %s: %v", e.Source, e.Err)
+	default:
+		return fmt.Sprintf("Error while copying %s to %s: %v", e.Source, e.Destination, e.Err)
+	}
+}
+
+// reported returns true if err was already printed as the error of a failed event
+func (p *eventPrinter) reported(err error) bool {
+	for _, f := range p.failures {
+		if errors.Is(err, f) {
+			return true
+		}
+	}
+	return false
+}
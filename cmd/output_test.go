@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventPrinterText(t *testing.T) {
+	cases := []struct {
+		name       string
+		input      s3transfer.Event
+		wantOut    string
+		wantErrOut string
+	}{
+		{"upload", s3transfer.Event{Op: s3transfer.OpUpload, Source: "a.txt", Destination: "s3://b/a.txt", Status: s3transfer.StatusSucceeded}, "upload a.txt to s3://b/a.txt\n", ""},
+		{"download", s3transfer.Event{Op: s3transfer.OpDownload, Source: "s3://b/a.txt", Destination: "a.txt", Status: s3transfer.StatusSucceeded}, "download s3://b/a.txt to a.txt\n", ""},
+		{"copy", s3transfer.Event{Op: s3transfer.OpCopy, Source: "s3://b/a.txt", Destination: "s3://c/a.txt", Status: s3transfer.StatusSucceeded}, "copy s3://b/a.txt to s3://c/a.txt\n", ""},
+		{"delete", s3transfer.Event{Op: s3transfer.OpDelete, Source: "s3://b/a.txt", Status: s3transfer.StatusSucceeded}, "s3://b/a.txt deleted\n", ""},
+		{"failed download", s3transfer.Event{Op: s3transfer.OpDownload, Source: "s3://b/a.txt", Destination: "a.txt", Status: s3transfer.StatusFailed, Err: errors.New("access denied")}, "", "Error while downloading s3://b/a.txt to a.txt: access denied\n"},
+		{"skipped download", s3transfer.Event{Op: s3transfer.OpDownload, Source: "s3://b/a.txt", Destination: "a.txt", Status: s3transfer.StatusSkipped, Err: &s3transfer.SkippedError{Err: errors.New("archived")}}, "", "Skipping s3://b/a.txt: archived\n"},
+		{"restore", s3transfer.Event{Op: s3transfer.OpRestore, Source: "s3://b/a.txt", Status: s3transfer.StatusSucceeded}, "restore requested for s3://b/a.txt\n", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var out, errOut bytes.Buffer
+			printer := &eventPrinter{out: &out, errOut: &errOut}
+			printer.print(c.input)
+
+			if out.String() != c.wantOut {
+				t.Errorf("got %q want %q", out.String(), c.wantOut)
+			}
+			if errOut.String() != c.wantErrOut {
+				t.Errorf("got %q want %q", errOut.String(), c.wantErrOut)
+			}
+		})
+	}
+}
+
+func TestEventPrinterJSON(t *testing.T) {
+	var out bytes.Buffer
+	printer, err := newEventPrinter("json", nil)
+	require.NoError(t, err)
+	printer.out = &out
+
+	deleteErr := &s3transfer.DeleteError{Code: "AccessDenied", Message: "Access Denied"}
+	printer.print(s3transfer.Event{Op: s3transfer.OpUpload, Source: "a.txt", Destination: "s3://b/a.txt", Bytes: 42, Duration: 1500 * time.Millisecond, Status: s3transfer.StatusSucceeded})
+	printer.print(s3transfer.Event{Op: s3transfer.OpDelete, Source: "s3://b/locked", Status: s3transfer.StatusFailed, Err: deleteErr})
+
+	dec := json.NewDecoder(&out)
+	var upload, failed map[string]any
+	require.NoError(t, dec.Decode(&upload))
+	require.NoError(t, dec.Decode(&failed))
+
+	require.Equal(t, "upload", upload["operation"])
+	require.Equal(t, "s3://b/a.txt", upload["destination"])
+	require.Equal(t, float64(42), upload["bytes"])
+	require.Equal(t, 1.5, upload["duration_seconds"])
+	require.Equal(t, "succeeded", upload["status"])
+	require.NotContains(t, upload, "error")
+
+	require.Equal(t, "failed", failed["status"])
+	require.Equal(t, "Access Denied", failed["error"])
+	require.Equal(t, "AccessDenied", failed["error_code"])
+	require.NotContains(t, failed, "destination")
+
+	require.True(t, printer.reported(deleteErr))
+	require.False(t, printer.reported(errors.New("other")))
+}
+
+func TestNewEventPrinterForError(t *testing.T) {
+	_, err := newEventPrinter("yaml", nil)
+	require.Error(t, err)
+}
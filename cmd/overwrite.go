@@ -0,0 +1,49 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+var globalNoClobber bool
+var globalOverwriteIfNewer bool
+var globalOverwriteIfDifferent bool
+var globalBackupSuffix string
+
+// overwrite policy from flags, at most one of them can be given
+func newOverwritePolicy() (s3transfer.OverwritePolicy, error) {
+	policy := s3transfer.OverwriteAlways
+	set := 0
+	for _, f := range []struct {
+		enabled bool
+		policy  s3transfer.OverwritePolicy
+	}{
+		{globalNoClobber, s3transfer.OverwriteNever},
+		{globalOverwriteIfNewer, s3transfer.OverwriteIfNewer},
+		{globalOverwriteIfDifferent, s3transfer.OverwriteIfDifferent},
+	} {
+		if f.enabled {
+			policy = f.policy
+			set++
+		}
+	}
+
+	if set > 1 {
+		return s3transfer.OverwriteAlways, errors.New("only one of no-clobber, overwrite-if-newer and overwrite-if-different can be given")
+	}
+	return policy, nil
+}
+
+// backup suffix from flags, it cannot move backups into other directories
+func newBackupSuffix() (string, error) {
+	if strings.ContainsAny(globalBackupSuffix, `/\`) {
+		return "", fmt.Errorf("backup-suffix cannot contain path separators, got %q", globalBackupSuffix)
+	}
+	return globalBackupSuffix, nil
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOverwritePolicy(t *testing.T) {
+	defer func(noClobber, ifNewer, ifDifferent bool) {
+		globalNoClobber, globalOverwriteIfNewer, globalOverwriteIfDifferent = noClobber, ifNewer, ifDifferent
+	}(globalNoClobber, globalOverwriteIfNewer, globalOverwriteIfDifferent)
+
+	cases := []struct {
+		name        string
+		noClobber   bool
+		ifNewer     bool
+		ifDifferent bool
+		want        s3transfer.OverwritePolicy
+		wantErr     bool
+	}{
+		{"default", false, false, false, s3transfer.OverwriteAlways, false},
+		{"no clobber", true, false, false, s3transfer.OverwriteNever, false},
+		{"if newer", false, true, false, s3transfer.OverwriteIfNewer, false},
+		{"if different", false, false, true, s3transfer.OverwriteIfDifferent, false},
+		{"conflicting", false, true, true, s3transfer.OverwriteAlways, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			globalNoClobber, globalOverwriteIfNewer, globalOverwriteIfDifferent = c.noClobber, c.ifNewer, c.ifDifferent
+			got, err := newOverwritePolicy()
+			if c.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
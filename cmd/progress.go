@@ -0,0 +1,273 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+const (
+	// files at least this large get their own progress bar while being transferred
+	progressBarThreshold = 64 * 1024 * 1024
+	progressBarWidth     = 30
+
+	ttyRefreshInterval     = 500 * time.Millisecond
+	summaryRefreshInterval = 10 * time.Second
+)
+
+// transferProgress keeps counters of a running transfer and periodically renders them.
+// On a terminal it redraws a live status block, otherwise it prints summary lines.
+// All methods are safe to call on a nil receiver, which disables progress reporting.
+type transferProgress struct {
+	filesTotal atomic.Int64
+	filesDone  atomic.Int64
+	bytesTotal atomic.Int64
+	bytesDone  atomic.Int64
+
+	out   io.Writer
+	live  bool
+	start time.Time
+
+	mu        sync.Mutex
+	files     map[*fileProgress]struct{}
+	drawn     int // number of lines drawn in last live render
+	stopch    chan struct{}
+	stoppedch chan struct{}
+}
+
+// fileProgress tracks a single file being transferred
+type fileProgress struct {
+	name     string
+	size     int64
+	done     atomic.Int64
+	progress *transferProgress
+}
+
+func newTransferProgress(out io.Writer, live bool) *transferProgress {
+	return &transferProgress{
+		out:   out,
+		live:  live,
+		files: make(map[*fileProgress]struct{}),
+	}
+}
+
+// isTerminal reports whether f is connected to a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+func (p *transferProgress) run() {
+	if p == nil {
+		return
+	}
+
+	p.start = time.Now()
+	p.stopch = make(chan struct{})
+	p.stoppedch = make(chan struct{})
+
+	interval := summaryRefreshInterval
+	if p.live {
+		interval = ttyRefreshInterval
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		defer close(p.stoppedch)
+		for {
+			select {
+			case <-p.stopch:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				p.render()
+				p.mu.Unlock()
+			}
+		}
+	}()
+}
+
+// stop ends periodic rendering and prints the final state
+func (p *transferProgress) stop() {
+	if p == nil || p.stopch == nil {
+		return
+	}
+
+	close(p.stopch)
+	<-p.stoppedch
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	fmt.Fprintln(p.out, p.summary())
+}
+
+// AddTotal registers a file found during listing or walking
+func (p *transferProgress) AddTotal(size int64) {
+	if p == nil {
+		return
+	}
+	p.filesTotal.Add(1)
+	p.bytesTotal.Add(size)
+}
+
+func (p *transferProgress) StartFile(name string, size int64) s3transfer.FileProgress {
+	fp := &fileProgress{name: name, size: size, progress: p}
+	if p == nil {
+		return fp
+	}
+
+	if size >= progressBarThreshold {
+		p.mu.Lock()
+		p.files[fp] = struct{}{}
+		p.mu.Unlock()
+	}
+	return fp
+}
+
+func (fp *fileProgress) Finish() {
+	p := fp.progress
+	if p == nil {
+		return
+	}
+
+	p.filesDone.Add(1)
+	p.mu.Lock()
+	delete(p.files, fp)
+	p.mu.Unlock()
+}
+
+func (fp *fileProgress) AddBytes(n int64) {
+	fp.done.Add(n)
+	if fp.progress == nil {
+		return
+	}
+	fp.progress.bytesDone.Add(n)
+}
+
+// fprintln prints a line of regular output to w without garbling the live status block
+func (p *transferProgress) fprintln(w io.Writer, a ...any) {
+	if p == nil {
+		fmt.Fprintln(w, a...)
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+	fmt.Fprintln(w, a...)
+	if p.live {
+		p.render()
+	}
+}
+
+// clear erases the live status block, must be called with mu held
+func (p *transferProgress) clear() {
+	if p.drawn == 0 {
+		return
+	}
+	// move cursor to the start of the block and erase till the end of screen
+	fmt.Fprintf(p.out, "\r\x1b[%dA\x1b[J", p.drawn)
+	p.drawn = 0
+}
+
+// render prints the current state, must be called with mu held
+func (p *transferProgress) render() {
+	if !p.live {
+		fmt.Fprintln(p.out, p.summary())
+		return
+	}
+
+	p.clear()
+	lines := []string{p.summary()}
+	for _, fp := range p.sortedFiles() {
+		lines = append(lines, fp.bar())
+	}
+	for _, l := range lines {
+		fmt.Fprintln(p.out, l)
+	}
+	p.drawn = len(lines)
+}
+
+func (p *transferProgress) sortedFiles() []*fileProgress {
+	files := make([]*fileProgress, 0, len(p.files))
+	for fp := range p.files {
+		files = append(files, fp)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].name < files[j].name
+	})
+	return files
+}
+
+func (p *transferProgress) summary() string {
+	elapsed := time.Since(p.start)
+	// totals may lag behind while listing is in progress or be unknown for single files
+	filesDone, filesTotal := p.filesDone.Load(), p.filesTotal.Load()
+	done, total := p.bytesDone.Load(), p.bytesTotal.Load()
+	filesTotal, total = max(filesTotal, filesDone), max(total, done)
+	rate := throughput(done, elapsed)
+
+	return fmt.Sprintf("%d/%d files\t%s/%s\t%s/s\tETA %s",
+		filesDone, filesTotal,
+		formatBytes(done), formatBytes(total),
+		formatBytes(int64(rate)),
+		formatETA(total-done, rate))
+}
+
+func (fp *fileProgress) bar() string {
+	done := fp.done.Load()
+	ratio := 0.0
+	if fp.size > 0 {
+		ratio = float64(done) / float64(fp.size)
+	}
+	ratio = min(max(ratio, 0), 1)
+
+	filled := int(ratio * progressBarWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressBarWidth-filled)
+	return fmt.Sprintf("  [%s] %3.0f%%  %s/%s  %s", bar, ratio*100, formatBytes(done), formatBytes(fp.size), fp.name)
+}
+
+// throughput in bytes per second
+func throughput(bytes int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(bytes) / elapsed.Seconds()
+}
+
+func formatETA(remaining int64, rate float64) string {
+	if rate <= 0 || remaining < 0 {
+		return "-"
+	}
+	return (time.Duration(float64(remaining)/rate) * time.Second).Truncate(time.Second).String()
+}
+
+// format byte count in IEC units i.e. 1.5 MiB
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatBytes(t *testing.T) {
+	cases := []struct {
+		name  string
+		input int64
+		want  string
+	}{
+		{"bytes", 512, "512 B"},
+		{"kibibytes", 1536, "1.5 KiB"},
+		{"mebibytes", 10 * 1024 * 1024, "10.0 MiB"},
+		{"gibibytes", 3 * 1024 * 1024 * 1024, "3.0 GiB"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := formatBytes(c.input)
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestFormatETA(t *testing.T) {
+	cases := []struct {
+		name           string
+		inputRemaining int64
+		inputRate      float64
+		want           string
+	}{
+		{"no throughput yet", 100, 0, "-"},
+		{"nothing remaining", 0, 10, "0s"},
+		{"minutes remaining", 1500, 10, "2m30s"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := formatETA(c.inputRemaining, c.inputRate)
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	require.Equal(t, 0.0, throughput(100, 0))
+	require.Equal(t, 50.0, throughput(100, 2*time.Second))
+}
+
+func TestProgressNilSafe(t *testing.T) {
+	var p *transferProgress
+	p.run()
+	p.AddTotal(10)
+	fp := p.StartFile("test.txt", 10)
+	fp.AddBytes(10)
+	fp.Finish()
+	p.stop()
+	require.Equal(t, int64(10), fp.(*fileProgress).done.Load())
+}
+
+func TestProgressCounters(t *testing.T) {
+	p := newTransferProgress(io.Discard, false)
+	p.AddTotal(10)
+	fp := p.StartFile("test.txt", 10)
+
+	// bodies rewound for retries report negative counts
+	fp.AddBytes(10)
+	fp.AddBytes(-10)
+	fp.AddBytes(4)
+	require.Equal(t, int64(4), p.bytesDone.Load())
+
+	fp.Finish()
+	require.Equal(t, int64(1), p.filesDone.Load())
+	require.Equal(t, int64(10), p.bytesTotal.Load())
+}
@@ -0,0 +1,160 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/smithy-go/middleware"
+	"golang.org/x/time/rate"
+)
+
+var rateUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1 << 10,
+	"kb":  1e3,
+	"kib": 1 << 10,
+	"m":   1 << 20,
+	"mb":  1e6,
+	"mib": 1 << 20,
+	"g":   1 << 30,
+	"gb":  1e9,
+	"gib": 1 << 30,
+}
+
+// parse rates like 50MiB/s, 1.5MB/s, 500k or 1024 into bytes per second
+func parseRate(s string) (float64, error) {
+	str := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), "/s")
+	idx := strings.IndexFunc(str, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if idx == -1 {
+		idx = len(str)
+	}
+
+	value, err := strconv.ParseFloat(str[:idx], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+
+	unit, ok := rateUnits[strings.TrimSpace(str[idx:])]
+	if !ok {
+		return 0, fmt.Errorf("invalid rate unit in %q", s)
+	}
+
+	if value <= 0 {
+		return 0, fmt.Errorf("rate must be positive %q", s)
+	}
+
+	return value * unit, nil
+}
+
+// parse a rate flag into bytes per second, 0 if the flag is not set
+func parseRateFlag(s string) (float64, error) {
+	if len(s) == 0 {
+		return 0, nil
+	}
+	return parseRate(s)
+}
+
+// operation types that can be limited separately with --max-rps-op
+var requestOperationTypes = []string{"list", "get", "head", "put", "delete"}
+
+// group S3 API operations into the types used for request rate limiting
+func operationType(operation string) string {
+	switch {
+	case strings.HasPrefix(operation, "List"):
+		return "list"
+	case strings.HasPrefix(operation, "Head"):
+		return "head"
+	case strings.HasPrefix(operation, "Get"):
+		return "get"
+	case strings.HasPrefix(operation, "Delete"), strings.HasPrefix(operation, "Abort"):
+		return "delete"
+	default:
+		// uploads, copies and other writes
+		return "put"
+	}
+}
+
+// requestLimiter throttles S3 API requests per second, in total and per operation type.
+// It is installed as a middleware so every request made through the client is accounted, including retries.
+// A single limiter is shared by all clients of a command, i.e. both endpoints of a copy between endpoints.
+type requestLimiter struct {
+	total *rate.Limiter
+	ops   map[string]*rate.Limiter
+}
+
+func newRequestLimiter(maxRPS float64, perOperation map[string]string) (*requestLimiter, error) {
+	if maxRPS < 0 {
+		return nil, fmt.Errorf("max-rps must not be negative")
+	}
+
+	rl := &requestLimiter{ops: make(map[string]*rate.Limiter)}
+	if maxRPS > 0 {
+		rl.total = newRequestRateLimiter(maxRPS)
+	}
+
+	for op, value := range perOperation {
+		if !slices.Contains(requestOperationTypes, op) {
+			return nil, fmt.Errorf("max-rps-op: unknown operation type %q, should be one of %s", op, strings.Join(requestOperationTypes, ", "))
+		}
+
+		rps, err := strconv.ParseFloat(value, 64)
+		if err != nil || rps <= 0 {
+			return nil, fmt.Errorf("max-rps-op: invalid rate %q for %s", value, op)
+		}
+		rl.ops[op] = newRequestRateLimiter(rps)
+	}
+
+	if rl.total == nil && len(rl.ops) == 0 {
+		return nil, nil
+	}
+	return rl, nil
+}
+
+// request limiter of the running command, nil when requests are not limited
+var commandRequestLimiter *requestLimiter
+
+// create the request limiter of the command from flags
+func startRequestLimiter() error {
+	var err error
+	commandRequestLimiter, err = newRequestLimiter(globalMaxRPS, globalMaxRPSPerOperation)
+	return err
+}
+
+func newRequestRateLimiter(rps float64) *rate.Limiter {
+	return rate.NewLimiter(rate.Limit(rps), max(int(rps), 1))
+}
+
+// block until a request for the operation is allowed
+func (rl *requestLimiter) wait(ctx context.Context, operation string) error {
+	if rl.total != nil {
+		if err := rl.total.Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	if l, ok := rl.ops[operationType(operation)]; ok {
+		return l.Wait(ctx)
+	}
+	return nil
+}
+
+// add rate limiting middleware after the retry middleware so that each attempt is throttled
+func (rl *requestLimiter) addMiddleware(stack *middleware.Stack) error {
+	return stack.Finalize.Insert(middleware.FinalizeMiddlewareFunc("RequestRateLimit",
+		func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+			if err := rl.wait(ctx, awsmiddleware.GetOperationName(ctx)); err != nil {
+				return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+			}
+			return next.HandleFinalize(ctx, in)
+		}), "Retry", middleware.After)
+}
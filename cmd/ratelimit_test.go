@@ -0,0 +1,124 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestParseRate(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+		want  float64
+	}{
+		{"plain bytes", "1024", 1024},
+		{"binary suffix per second", "50MiB/s", 50 * 1024 * 1024},
+		{"decimal suffix", "1.5MB/s", 1.5e6},
+		{"short suffix", "500k", 500 * 1024},
+		{"upper case with space", "2 GiB/s", 2 * 1024 * 1024 * 1024},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := parseRate(c.input)
+			require.NoError(t, err)
+
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestParseRateForError(t *testing.T) {
+	cases := []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"unknown unit", "10XB/s"},
+		{"zero", "0"},
+		{"negative", "-5MiB"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := parseRate(c.input)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestOperationType(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+	}{
+		{"ListObjectsV2", "list"},
+		{"HeadObject", "head"},
+		{"GetObject", "get"},
+		{"PutObject", "put"},
+		{"CopyObject", "put"},
+		{"UploadPart", "put"},
+		{"DeleteObjects", "delete"},
+		{"AbortMultipartUpload", "delete"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			got := operationType(c.input)
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestNewRequestLimiter(t *testing.T) {
+	rl, err := newRequestLimiter(0, nil)
+	require.NoError(t, err)
+	require.Nil(t, rl, "no limiter expected without limits")
+
+	rl, err = newRequestLimiter(50, map[string]string{"list": "10", "delete": "2.5"})
+	require.NoError(t, err)
+	require.Equal(t, rate.Limit(50), rl.total.Limit())
+	require.Equal(t, rate.Limit(10), rl.ops["list"].Limit())
+	require.Equal(t, rate.Limit(2.5), rl.ops["delete"].Limit())
+}
+
+func TestNewRequestLimiterForError(t *testing.T) {
+	cases := []struct {
+		name       string
+		inputRPS   float64
+		inputPerOp map[string]string
+	}{
+		{"negative rps", -1, nil},
+		{"unknown operation", 0, map[string]string{"copy": "10"}},
+		{"invalid rate", 0, map[string]string{"list": "fast"}},
+		{"zero rate", 0, map[string]string{"list": "0"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := newRequestLimiter(c.inputRPS, c.inputPerOp)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestStartRequestLimiter(t *testing.T) {
+	defer func(rps float64, perOp map[string]string, rl *requestLimiter) {
+		globalMaxRPS, globalMaxRPSPerOperation, commandRequestLimiter = rps, perOp, rl
+	}(globalMaxRPS, globalMaxRPSPerOperation, commandRequestLimiter)
+
+	// clients of the command, i.e. source and destination of a copy, share the limiter
+	globalMaxRPS, globalMaxRPSPerOperation = 10, nil
+	require.NoError(t, startRequestLimiter())
+	require.NotNil(t, commandRequestLimiter)
+	require.Equal(t, rate.Limit(10), commandRequestLimiter.total.Limit())
+
+	globalMaxRPS = -1
+	require.Error(t, startRequestLimiter())
+}
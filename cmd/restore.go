@@ -0,0 +1,198 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+	"github.com/spf13/cobra"
+)
+
+// restoreCmd represents the restore command
+var restoreCmd = &cobra.Command{
+	Use:   "restore",
+	Short: "Restore archived S3 objects from Glacier and Deep Archive",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if globalRestoreStatus {
+			restoreStatus(cmd.Context(), args)
+			return
+		}
+		restoreS3(cmd.Context(), args)
+	},
+}
+
+var globalRestoreDays int32
+var globalRestoreTier string
+var globalRestoreStatus bool
+var globalArchived string
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().Int32Var(&globalRestoreDays, "days", 1, "number of days restored copies are kept")
+	restoreCmd.Flags().StringVar(&globalRestoreTier, "tier", "", "retrieval tier, Bulk, Standard or Expedited, default is Standard")
+	restoreCmd.Flags().BoolVar(&globalRestoreStatus, "status", false, "report restore progress of archived objects instead of restoring them")
+}
+
+// restore settings from flags
+func newRestoreParams() (s3transfer.RestoreParams, error) {
+	params := s3transfer.RestoreParams{Days: globalRestoreDays}
+	if globalRestoreDays <= 0 {
+		return params, fmt.Errorf("days should be positive, got %d", globalRestoreDays)
+	}
+
+	if len(globalRestoreTier) == 0 {
+		return params, nil
+	}
+	for _, t := range types.Tier("").Values() {
+		if strings.EqualFold(string(t), globalRestoreTier) {
+			params.Tier = t
+			return params, nil
+		}
+	}
+	return params, fmt.Errorf("tier should be Bulk, Standard or Expedited, got %q", globalRestoreTier)
+}
+
+// handling of archived objects by cp from flags
+func newArchivedPolicy() (s3transfer.ArchivedPolicy, error) {
+	switch globalArchived {
+	case "", "fail":
+		return s3transfer.ArchivedFail, nil
+	case "skip":
+		return s3transfer.ArchivedSkip, nil
+	case "wait":
+		return s3transfer.ArchivedWait, nil
+	default:
+		return s3transfer.ArchivedFail, fmt.Errorf("archived should be fail, skip or wait, got %q", globalArchived)
+	}
+}
+
+func restoreS3(ctx context.Context, paths []string) {
+	var total s3transfer.Result
+	var firstErr error
+	start := time.Now()
+	for alias, aliasPaths := range groupByAlias(paths) {
+		result, err := restoreS3WithAlias(ctx, alias, aliasPaths)
+		total.Add(result)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	reportRun(ctx, os.Stderr, "restore", total, start, firstErr)
+}
+
+func restoreS3WithAlias(ctx context.Context, alias string, paths []string) (s3transfer.Result, error) {
+	params, err := newRestoreParams()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return s3transfer.Result{}, err
+	}
+
+	opts, err := newTransferOptions()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
+		return s3transfer.Result{}, err
+	}
+
+	printer, err := newEventPrinter(globalOutput, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return s3transfer.Result{}, err
+	}
+	opts.OnEvent = printer.print
+
+	client, err := newClient(ctx, alias, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
+		return s3transfer.Result{}, err
+	}
+
+	result, err := client.Restore(ctx, stripAliases(paths), params)
+	if err != nil && !printer.reported(err) {
+		fmt.Fprintln(os.Stderr, "Error while restoring objects", err)
+	}
+	return result, err
+}
+
+func restoreStatus(ctx context.Context, paths []string) {
+	if globalOutput != "text" && globalOutput != "json" {
+		fmt.Fprintf(os.Stderr, "output: unknown format %q, should be text or json\n", globalOutput)
+		return
+	}
+
+	for alias, aliasPaths := range groupByAlias(paths) {
+		opts, err := newTransferOptions()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
+			return
+		}
+
+		client, err := newClient(ctx, alias, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
+			return
+		}
+
+		statuses, err := client.RestoreStatus(ctx, stripAliases(aliasPaths))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error while reading restore status", err)
+			return
+		}
+		printRestoreStatuses(os.Stdout, statuses, globalOutput == "json")
+	}
+}
+
+// restoreStatusRecord is the JSON representation of a restore status
+type restoreStatusRecord struct {
+	Path         string     `json:"path"`
+	StorageClass string     `json:"storage_class"`
+	State        string     `json:"state"`
+	Expiry       *time.Time `json:"expiry,omitempty"`
+}
+
+func restoreState(s s3transfer.RestoreStatus) string {
+	switch {
+	case s.Restored():
+		return "restored"
+	case s.Ongoing:
+		return "in-progress"
+	default:
+		return "archived"
+	}
+}
+
+func printRestoreStatuses(w io.Writer, statuses []s3transfer.RestoreStatus, asJSON bool) {
+	for _, s := range statuses {
+		if asJSON {
+			// record has no values that cannot be marshalled
+			b, _ := json.Marshal(restoreStatusRecord{Path: s.Path, StorageClass: string(s.StorageClass), State: restoreState(s), Expiry: s.Expiry})
+			fmt.Fprintln(w, string(b))
+			continue
+		}
+
+		expiry := ""
+		if s.Expiry != nil {
+			expiry = s.Expiry.UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%-11s\t%-12s\t%-20s\t%s\n", restoreState(s), s.StorageClass, expiry, s.Path)
+	}
+}
+
+// s3 paths without their aliases, clients are created per alias
+func stripAliases(paths []string) []string {
+	s3paths := make([]string, 0, len(paths))
+	for _, p := range paths {
+		_, p = splitAlias(p)
+		s3paths = append(s3paths, p)
+	}
+	return s3paths
+}
@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRestoreParams(t *testing.T) {
+	defer func(days int32, tier string) {
+		globalRestoreDays, globalRestoreTier = days, tier
+	}(globalRestoreDays, globalRestoreTier)
+
+	cases := []struct {
+		name    string
+		days    int32
+		tier    string
+		want    s3transfer.RestoreParams
+		wantErr bool
+	}{
+		{"default tier", 1, "", s3transfer.RestoreParams{Days: 1}, false},
+		{"lowercase tier", 7, "bulk", s3transfer.RestoreParams{Days: 7, Tier: types.TierBulk}, false},
+		{"unknown tier", 1, "fast", s3transfer.RestoreParams{}, true},
+		{"zero days", 0, "", s3transfer.RestoreParams{}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			globalRestoreDays, globalRestoreTier = c.days, c.tier
+			got, err := newRestoreParams()
+			if c.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestCpRestoreFlags(t *testing.T) {
+	defer func(days int32, tier string) {
+		globalRestoreDays, globalRestoreTier = days, tier
+	}(globalRestoreDays, globalRestoreTier)
+
+	// restores requested by cp --archived wait take the same flags as restore
+	require.NoError(t, cpCmd.Flags().Set("days", "3"))
+	require.NoError(t, cpCmd.Flags().Set("tier", "expedited"))
+	got, err := newRestoreParams()
+	require.NoError(t, err)
+	require.Equal(t, s3transfer.RestoreParams{Days: 3, Tier: types.TierExpedited}, got)
+}
+
+func TestPrintRestoreStatuses(t *testing.T) {
+	expiry := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	statuses := []s3transfer.RestoreStatus{
+		{Path: "s3://b/a", StorageClass: types.StorageClassGlacier},
+		{Path: "s3://b/b", StorageClass: types.StorageClassDeepArchive, Ongoing: true},
+		{Path: "s3://b/c", StorageClass: types.StorageClassGlacier, Expiry: &expiry},
+	}
+
+	var out bytes.Buffer
+	printRestoreStatuses(&out, statuses, false)
+	want := "archived   \tGLACIER     \t                    \ts3://b/a\n" +
+		"in-progress\tDEEP_ARCHIVE\t                    \ts3://b/b\n" +
+		"restored   \tGLACIER     \t2024-05-01T00:00:00Z\ts3://b/c\n"
+	if out.String() != want {
+		t.Errorf("got %q want %q", out.String(), want)
+	}
+
+	out.Reset()
+	printRestoreStatuses(&out, statuses[2:], true)
+	require.JSONEq(t, `{"path":"s3://b/c","storage_class":"GLACIER","state":"restored","expiry":"2024-05-01T00:00:00Z"}`, out.String())
+}
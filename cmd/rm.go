@@ -7,11 +7,9 @@ import (
 	"context"
 	"fmt"
 	"os"
-	"strings"
+	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
 	"github.com/spf13/cobra"
 )
 
@@ -20,7 +18,7 @@ var rmCmd = &cobra.Command{
 	Use:   "rm",
 	Short: "Remove S3 files",
 	Run: func(cmd *cobra.Command, args []string) {
-		removeS3(args)
+		removeS3(cmd.Context(), args)
 	},
 }
 
@@ -28,140 +26,43 @@ func init() {
 	rootCmd.AddCommand(rmCmd)
 }
 
-func removeS3(paths []string) {
-	client, err := newClient()
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
-		return
-	}
-
-	globs, regulars := splitGlobsAndRegulars(paths)
-	err = client.removePaths(regulars)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error while removing keys", err)
-		return
-	}
-
-	err = client.removeGlobs(globs)
-	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error while removing keys", err)
-		return
-	}
-}
-
-func splitGlobsAndRegulars(paths []string) (globs, regulars []string) {
-	for _, p := range paths {
-		if strings.HasSuffix(p, "*") {
-			globs = append(globs, p)
-			continue
-		}
-		regulars = append(regulars, p)
-	}
-	return
-}
-
-// group paths by bucket name i.e b1: [k1,k2,k3], b2: [k4, k5]
-func groupByBucket(paths []string) (map[string][]string, error) {
-	groupByBucket := make(map[string][]string)
-	for _, p := range paths {
-		bucket, key, err := extractBucketAndKey(p)
-		if err != nil {
-			return nil, err
+func removeS3(ctx context.Context, paths []string) {
+	var total s3transfer.Result
+	var firstErr error
+	start := time.Now()
+	for alias, aliasPaths := range groupByAlias(paths) {
+		result, err := removeS3WithAlias(ctx, alias, aliasPaths)
+		total.Add(result)
+		if firstErr == nil {
+			firstErr = err
 		}
-		groupByBucket[bucket] = append(groupByBucket[bucket], key)
 	}
-	return groupByBucket, nil
+	reportRun(ctx, os.Stderr, "rm", total, start, firstErr)
 }
 
-func (s *s3client) removeGlobs(paths []string) error {
-	bucketGroups, err := groupByBucket(paths)
+func removeS3WithAlias(ctx context.Context, alias string, paths []string) (s3transfer.Result, error) {
+	opts, err := newTransferOptions()
 	if err != nil {
-		return nil
-	}
-
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	fnch := make(chan func() error, globalMaxParallelRequests)
-	outch := make(chan string, globalMaxParallelRequests)
-	wg := s.runPooled(cancel, fnch, outch)
-
-	for b, keys := range bucketGroups {
-		for _, k := range keys {
-			fnch <- func() error {
-				err := s.removeGlob(ctx, b, k, outch)
-				if err != nil {
-					return err
-				}
-				outch <- fmt.Sprintf("%s deleted", generateS3Path(b, k))
-				return err
-			}
-		}
+		fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
+		return s3transfer.Result{}, err
 	}
 
-	close(fnch)
-	wg.Wait()
-	return nil
-}
-
-func (s *s3client) removeGlob(ctx context.Context, bucket, prefix string, outch chan<- string) error {
-	prefix = strings.TrimSuffix(prefix, "*")
-	return s.listObject(ctx, listParams{bucket: bucket, prefix: aws.String(prefix)}, func(output *s3.ListObjectsV2Output) {
-		keys := make([]string, 0, len(output.Contents))
-		for _, o := range output.Contents {
-			keys = append(keys, aws.ToString(o.Key))
-		}
-		deleteOutput, err := s.removeObjects(bucket, keys)
-		if err != nil {
-			return
-		}
-
-		for _, d := range deleteOutput.Deleted {
-			outch <- fmt.Sprintf("%s deleted", aws.ToString(d.Key))
-		}
-
-		for _, err := range deleteOutput.Errors {
-			outch <- fmt.Sprintf("Error while deleting %s: %s", aws.ToString(err.Key), aws.ToString(err.Message))
-		}
-
-	})
-}
-
-func (s *s3client) removePaths(paths []string) error {
-	bucketGroups, err := groupByBucket(paths)
+	printer, err := newEventPrinter(globalOutput, nil)
 	if err != nil {
-		return nil
+		fmt.Fprintln(os.Stderr, err)
+		return s3transfer.Result{}, err
 	}
-	for b, keys := range bucketGroups {
-		output, err := s.removeObjects(b, keys)
-		if err != nil {
-			return err
-		}
+	opts.OnEvent = printer.print
 
-		for _, d := range output.Deleted {
-			fmt.Printf("%s deleted\n", aws.ToString(d.Key))
-		}
-
-		for _, err := range output.Errors {
-			fmt.Fprintf(os.Stderr, "Error while deleting %s: %s\n", aws.ToString(err.Key), aws.ToString(err.Message))
-		}
-	}
-
-	return nil
-}
-
-func (s *s3client) removeObjects(bucket string, keys []string) (*s3.DeleteObjectsOutput, error) {
-	delete := types.Delete{}
-	for _, k := range keys {
-		delete.Objects = append(delete.Objects, types.ObjectIdentifier{Key: aws.String(k)})
+	client, err := newClient(ctx, alias, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
+		return s3transfer.Result{}, err
 	}
 
-	output, err := s.client.DeleteObjects(context.Background(), &s3.DeleteObjectsInput{
-		Bucket: aws.String(bucket),
-		Delete: &delete,
-	})
-	if err != nil {
-		return output, err
+	result, err := client.Remove(ctx, stripAliases(paths))
+	if err != nil && !printer.reported(err) {
+		fmt.Fprintln(os.Stderr, "Error while removing keys", err)
 	}
-	return output, nil
+	return result, err
 }
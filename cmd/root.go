@@ -4,7 +4,10 @@ Copyright © 2024 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"log/slog"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 )
@@ -13,21 +16,112 @@ import (
 var rootCmd = &cobra.Command{
 	Use:   "s3cli",
 	Short: "Some S3 utilities",
+
+	PersistentPreRunE: prepareCommand,
+}
+
+// cancels context created for --timeout
+var cancelTimeout context.CancelFunc = func() {}
+
+// load configuration file, configure logging and apply global timeout to the command context
+func prepareCommand(cmd *cobra.Command, args []string) error {
+	if err := loadConfigFile(cmd, args); err != nil {
+		return err
+	}
+
+	logger, err := newLogger(os.Stderr, globalLogFormat, logLevel())
+	if err != nil {
+		return err
+	}
+	slog.SetDefault(logger.With("command", cmd.Name()))
+
+	if err := startMetrics(); err != nil {
+		return err
+	}
+
+	if err := startRequestLimiter(); err != nil {
+		return err
+	}
+
+	if globalTimeout > 0 {
+		ctx, cancel := context.WithTimeout(cmd.Context(), globalTimeout)
+		cmd.SetContext(ctx)
+		cancelTimeout = cancel
+	}
+	return nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+	ctx, stop := notifyInterrupt(context.Background())
+	err := rootCmd.ExecuteContext(ctx)
+	writeMetricsFile()
+	cancelTimeout()
+	stop()
+
+	if isInterrupted(ctx) {
+		os.Exit(interruptedExitCode)
+	}
+
 	if err != nil {
 		os.Exit(1)
 	}
 }
 
-var globalS3Endpoint string
 var globalMaxParallelRequests int
+var globalLimitRate string
+var globalLimitUploadRate string
+var globalLimitDownloadRate string
+var globalMaxRPS float64
+var globalMaxRPSPerOperation map[string]string
+var globalTimeout time.Duration
+var globalRequestTimeout time.Duration
 
 func init() {
-	rootCmd.PersistentFlags().StringVarP(&globalS3Endpoint, "endpoint", "e", "", "Use alternative endpoint")
+	rootCmd.PersistentFlags().StringVar(&globalConfigFile, "config", "", "Configuration file with aliases and flag defaults (default s3cli/config.yaml under user config directory, i.e. ~/.config)")
+	rootCmd.PersistentFlags().StringVarP(&globalClientOptions.endpoint, "endpoint", "e", "", "Use alternative endpoint")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.profile, "profile", "", "Use a named profile from shared config files")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.region, "region", "", "Use given region instead of the one from environment or profile")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.accessKey, "access-key", "", "Access key id, requires --secret-key")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.secretKey, "secret-key", "", "Secret access key, requires --access-key")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.sessionToken, "session-token", "", "Session token for temporary credentials")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.credentialsFile, "credentials-file", "", "Read credentials from given shared credentials file")
+	rootCmd.PersistentFlags().BoolVar(&globalClientOptions.noSignRequest, "no-sign-request", false, "Do not sign requests, for accessing public buckets")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.roleARN, "role-arn", "", "Assume given role before accessing S3")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.roleSessionName, "role-session-name", "", "Session name used while assuming role")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.externalID, "external-id", "", "External id used while assuming role")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.mfaSerial, "mfa-serial", "", "MFA device serial number used while assuming role, token code is read from stdin")
+
+	rootCmd.PersistentFlags().VarPF(optionalBoolFlag{target: &globalClientOptions.pathStyle, value: true}, "path-style", "", "Use path style addressing i.e. https://endpoint/bucket/key").NoOptDefVal = "true"
+	rootCmd.PersistentFlags().VarPF(optionalBoolFlag{target: &globalClientOptions.pathStyle, value: false}, "virtual-host", "", "Use virtual hosted style addressing i.e. https://bucket.endpoint/key").NoOptDefVal = "true"
+	rootCmd.MarkFlagsMutuallyExclusive("path-style", "virtual-host")
+	rootCmd.PersistentFlags().BoolVar(&globalClientOptions.useDualStack, "use-dualstack", false, "Use dual-stack (IPv4 and IPv6) endpoints")
+	rootCmd.PersistentFlags().BoolVar(&globalClientOptions.useFIPS, "use-fips", false, "Use FIPS endpoints")
+	rootCmd.PersistentFlags().BoolVar(&globalClientOptions.useAccelerate, "use-accelerate", false, "Use S3 Transfer Acceleration endpoints")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.caBundle, "ca-bundle", "", "PEM file with additional CA certificates to trust")
+	rootCmd.PersistentFlags().BoolVar(&globalClientOptions.insecureSkipVerify, "insecure-skip-verify", false, "Do not verify TLS certificates, for test clusters only")
+	rootCmd.PersistentFlags().StringVar(&globalClientOptions.proxy, "proxy", "", "HTTP(S) proxy url, by default HTTPS_PROXY/HTTP_PROXY environment variables are used")
+	rootCmd.PersistentFlags().DurationVar(&globalClientOptions.connectTimeout, "connect-timeout", 0, "Timeout for establishing connections, 0 means SDK default")
+	rootCmd.PersistentFlags().DurationVar(&globalClientOptions.tlsHandshakeTimeout, "tls-handshake-timeout", 0, "Timeout for TLS handshakes, 0 means SDK default")
+	rootCmd.PersistentFlags().DurationVar(&globalClientOptions.responseHeaderTimeout, "response-header-timeout", 0, "Timeout for waiting response headers, 0 means SDK default")
+	rootCmd.PersistentFlags().DurationVar(&globalClientOptions.idleConnTimeout, "idle-conn-timeout", 0, "Time after which idle connections are closed, 0 means SDK default")
+	rootCmd.PersistentFlags().IntVar(&globalClientOptions.maxIdleConns, "max-idle-conns", 0, "Maximum number of idle connections kept in pool, 0 means SDK default")
+	rootCmd.PersistentFlags().IntVar(&globalClientOptions.maxConnsPerHost, "max-conns-per-host", 0, "Maximum number of connections per host, 0 means unlimited")
 	rootCmd.PersistentFlags().IntVarP(&globalMaxParallelRequests, "max-parallel-requests", "m", 10, "Number of maximum requests to run in parallel")
+	rootCmd.PersistentFlags().StringVar(&globalLimitRate, "limit-rate", "", "Limit total bandwidth of all transfers, i.e. 50MiB/s")
+	rootCmd.PersistentFlags().StringVar(&globalLimitUploadRate, "limit-upload-rate", "", "Limit bandwidth of uploads, i.e. 10MiB/s")
+	rootCmd.PersistentFlags().StringVar(&globalLimitDownloadRate, "limit-download-rate", "", "Limit bandwidth of downloads, i.e. 10MiB/s")
+	rootCmd.PersistentFlags().Float64Var(&globalMaxRPS, "max-rps", 0, "Maximum number of S3 requests per second, 0 means unlimited")
+	rootCmd.PersistentFlags().DurationVar(&globalTimeout, "timeout", 0, "Timeout of the whole command, i.e. 30m, 0 means no timeout")
+	rootCmd.PersistentFlags().DurationVar(&globalRequestTimeout, "request-timeout", 0, "Timeout of each S3 request including transfer of its body, 0 means no timeout")
+	rootCmd.PersistentFlags().BoolVarP(&globalVerbose, "verbose", "v", false, "Log failed and retried requests")
+	rootCmd.PersistentFlags().BoolVar(&globalDebug, "debug", false, "Log every request and debugging details")
+	rootCmd.PersistentFlags().StringVar(&globalLogFormat, "log-format", "text", "Format of logs written to stderr, text or json")
+	rootCmd.PersistentFlags().StringVarP(&globalOutput, "output", "o", "text", "Output format of cp and rm events, text or json (one object per line)")
+	rootCmd.PersistentFlags().StringVar(&globalReport, "report", "", "Write a JSON summary of cp and rm runs to given file")
+	rootCmd.PersistentFlags().StringVar(&globalMetricsAddr, "metrics-addr", "", "Expose Prometheus metrics on given address under /metrics, i.e. :9090")
+	rootCmd.PersistentFlags().StringVar(&globalMetricsFile, "metrics-file", "", "Write final metrics in Prometheus text format to given file")
+	rootCmd.PersistentFlags().BoolVar(&globalClientOptions.logWire, "log-wire", false, "Log HTTP requests and responses of the SDK without bodies, implies --debug")
+	rootCmd.PersistentFlags().StringToStringVar(&globalMaxRPSPerOperation, "max-rps-op", nil, "Maximum number of S3 requests per second by operation type (list, get, head, put, delete), i.e. list=10,delete=5")
 }
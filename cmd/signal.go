@@ -0,0 +1,77 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+// exit code used when s3cli is stopped by a signal, as shells do for SIGINT
+const interruptedExitCode = 130
+
+var errInterrupted = errors.New("interrupted")
+
+// time in-flight transfers are given to finish after an interrupt before they are aborted
+var interruptGracePeriod = 30 * time.Second
+
+// return a context that is cancelled with errInterrupted on the first SIGINT or SIGTERM. Cancelling it stops
+// new transfers, in-flight ones are left to finish or roll back until interruptGracePeriod passes.
+// A second signal exits immediately.
+func notifyInterrupt(parent context.Context) (context.Context, context.CancelFunc) {
+	abort, cancelAbort := context.WithCancelCause(parent)
+	ctx, cancel := context.WithCancelCause(s3transfer.WithAbort(parent, abort))
+	stopped := make(chan struct{})
+
+	sigch := make(chan os.Signal, 1)
+	signal.Notify(sigch, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case <-sigch:
+		case <-stopped:
+			return
+		}
+
+		fmt.Fprintf(os.Stderr, "Interrupted, waiting up to %s for in-flight transfers to finish. Press Ctrl-C again to exit immediately\n", interruptGracePeriod)
+		cancel(errInterrupted)
+
+		timer := time.NewTimer(interruptGracePeriod)
+		defer timer.Stop()
+		select {
+		case <-sigch:
+			os.Exit(interruptedExitCode)
+		case <-timer.C:
+			fmt.Fprintln(os.Stderr, "In-flight transfers did not finish in time, aborting them")
+			cancelAbort(errInterrupted)
+		case <-stopped:
+			return
+		}
+
+		select {
+		case <-sigch:
+			os.Exit(interruptedExitCode)
+		case <-stopped:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(sigch)
+		close(stopped)
+		cancel(context.Canceled)
+		cancelAbort(context.Canceled)
+	}
+}
+
+// check whether ctx is cancelled because of an interrupt signal
+func isInterrupted(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errInterrupted)
+}
@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsInterrupted(t *testing.T) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	cancel(errInterrupted)
+	require.True(t, isInterrupted(ctx))
+
+	ctx, cancel = context.WithCancelCause(context.Background())
+	cancel(nil)
+	require.False(t, isInterrupted(ctx))
+}
@@ -0,0 +1,56 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+var globalSSE string
+var globalSSEKMSKeyID string
+var globalSSEBucketKey *bool
+var globalSSECKeyFile string
+
+// server side encryption settings from flags
+func newEncryption() (s3transfer.Encryption, error) {
+	e := s3transfer.Encryption{
+		Algorithm:        types.ServerSideEncryption(globalSSE),
+		KMSKeyID:         globalSSEKMSKeyID,
+		BucketKeyEnabled: globalSSEBucketKey,
+	}
+
+	if len(globalSSECKeyFile) != 0 {
+		key, err := readKeyFile(globalSSECKeyFile)
+		if err != nil {
+			return e, err
+		}
+		e.CustomerKey = key
+	}
+
+	return e, e.Validate()
+}
+
+// read 256 bit key from file, either as raw bytes or base64 encoded
+func readKeyFile(path string) ([]byte, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read key %w", err)
+	}
+
+	if len(b) == 32 {
+		return b, nil
+	}
+
+	key, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(b)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("key in %s should be 32 bytes, raw or base64 encoded", path)
+	}
+	return key, nil
+}
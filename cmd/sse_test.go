@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadKeyFile(t *testing.T) {
+	key := bytes.Repeat([]byte{'k'}, 32)
+	cases := []struct {
+		name    string
+		input   []byte
+		wantErr bool
+	}{
+		{"raw", key, false},
+		{"base64", []byte(base64.StdEncoding.EncodeToString(key) + "\n"), false},
+		{"short", key[:16], true},
+		{"short base64", []byte(base64.StdEncoding.EncodeToString(key[:16])), true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "key")
+			require.NoError(t, os.WriteFile(path, c.input, 0600))
+
+			got, err := readKeyFile(path)
+			if c.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, key, got)
+		})
+	}
+}
@@ -0,0 +1,61 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+var globalStorageClass string
+var globalACL string
+var globalGrants []string
+var globalTagging string
+var globalMetadataDirective string
+
+// storage class, access control and tags of written objects from flags
+func newStorageOptions() (s3transfer.StorageOptions, error) {
+	o := s3transfer.StorageOptions{
+		StorageClass: types.StorageClass(strings.ToUpper(globalStorageClass)),
+		ACL:          types.ObjectCannedACL(globalACL),
+		Tagging:      globalTagging,
+	}
+
+	for _, g := range globalGrants {
+		permission, grantee, ok := strings.Cut(g, "=")
+		if !ok || len(grantee) == 0 {
+			return o, fmt.Errorf("grant should be permission=grantee i.e. read=id=abc, got %q", g)
+		}
+
+		var target *string
+		switch permission {
+		case "read":
+			target = &o.GrantRead
+		case "read-acp":
+			target = &o.GrantReadACP
+		case "write-acp":
+			target = &o.GrantWriteACP
+		case "full-control":
+			target = &o.GrantFullControl
+		default:
+			return o, fmt.Errorf("grant permission should be read, read-acp, write-acp or full-control, got %q", permission)
+		}
+
+		if len(*target) != 0 {
+			*target += ", "
+		}
+		*target += grantee
+	}
+
+	return o, o.Validate()
+}
+
+// directive of copies between S3 paths from flags
+func newMetadataDirective() (types.MetadataDirective, error) {
+	d := types.MetadataDirective(strings.ToUpper(globalMetadataDirective))
+	return d, s3transfer.ValidateMetadataDirective(d)
+}
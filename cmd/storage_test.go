@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStorageOptions(t *testing.T) {
+	defer func(storageClass string, grants []string) {
+		globalStorageClass, globalGrants = storageClass, grants
+	}(globalStorageClass, globalGrants)
+
+	cases := []struct {
+		name         string
+		storageClass string
+		grants       []string
+		want         s3transfer.StorageOptions
+		wantErr      bool
+	}{
+		{"empty", "", nil, s3transfer.StorageOptions{}, false},
+		{"lowercase storage class", "deep_archive", nil, s3transfer.StorageOptions{StorageClass: types.StorageClassDeepArchive}, false},
+		{"grants", "", []string{"read=id=a", "read=uri=http://acs.amazonaws.com/groups/global/AllUsers", "full-control=id=b"},
+			s3transfer.StorageOptions{GrantRead: "id=a, uri=http://acs.amazonaws.com/groups/global/AllUsers", GrantFullControl: "id=b"}, false},
+		{"unknown permission", "", []string{"write=id=a"}, s3transfer.StorageOptions{}, true},
+		{"missing grantee", "", []string{"read"}, s3transfer.StorageOptions{}, true},
+		{"unknown storage class", "cold", nil, s3transfer.StorageOptions{}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			globalStorageClass, globalGrants = c.storageClass, c.grants
+			got, err := newStorageOptions()
+			if c.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, c.want, got)
+		})
+	}
+}
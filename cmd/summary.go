@@ -0,0 +1,103 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+)
+
+var globalReport string
+
+// retries of all S3 requests made by the command
+var requestRetries = &retryCounter{}
+
+// retryCounter counts retried attempts of S3 requests
+type retryCounter struct {
+	count atomic.Int64
+}
+
+func (rc *retryCounter) addMiddleware(stack *middleware.Stack) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("RetryCounting",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			out, metadata, err := next.HandleInitialize(ctx, in)
+			// attempt results are recorded by the retry middleware whether the operation failed or not
+			if results, ok := retry.GetAttemptResults(metadata); ok && len(results.Results) > 1 {
+				rc.count.Add(int64(len(results.Results) - 1))
+			}
+			return out, metadata, err
+		}), middleware.After)
+}
+
+// runSummary is printed at the end of cp and rm, and written to the file given with --report
+type runSummary struct {
+	Command        string  `json:"command"`
+	Completed      int64   `json:"completed"`
+	Failed         int64   `json:"failed"`
+	Skipped        int64   `json:"skipped"`
+	Bytes          int64   `json:"bytes"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	BytesPerSecond float64 `json:"bytes_per_second"`
+	Retries        int64   `json:"retries"`
+	Interrupted    bool    `json:"interrupted"`
+	Error          string  `json:"error,omitempty"`
+}
+
+func newRunSummary(command string, result s3transfer.Result, elapsed time.Duration, retries int64, err error) runSummary {
+	s := runSummary{
+		Command:        command,
+		Completed:      result.Completed,
+		Failed:         result.Failed,
+		Skipped:        result.Skipped,
+		Bytes:          result.Bytes,
+		ElapsedSeconds: elapsed.Seconds(),
+		BytesPerSecond: throughput(result.Bytes, elapsed),
+		Retries:        retries,
+	}
+	if err != nil {
+		s.Error = err.Error()
+	}
+	return s
+}
+
+func (s runSummary) String() string {
+	title := "Summary"
+	if s.Interrupted {
+		title = "Interrupted"
+	}
+	elapsed := time.Duration(s.ElapsedSeconds * float64(time.Second)).Round(time.Millisecond)
+	return fmt.Sprintf("%s: %d completed, %d failed, %d skipped, %s in %s (%s/s), %d retries",
+		title, s.Completed, s.Failed, s.Skipped, formatBytes(s.Bytes), elapsed, formatBytes(int64(s.BytesPerSecond)), s.Retries)
+}
+
+// print summary of a finished run to w and write it to the --report file if given
+func reportRun(ctx context.Context, w io.Writer, command string, result s3transfer.Result, start time.Time, err error) {
+	summary := newRunSummary(command, result, time.Since(start), requestRetries.count.Load(), err)
+	summary.Interrupted = isInterrupted(ctx)
+	fmt.Fprintln(w, summary)
+
+	if len(globalReport) == 0 {
+		return
+	}
+	if err := writeReport(globalReport, summary); err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot write report", err)
+	}
+}
+
+func writeReport(path string, summary runSummary) error {
+	b, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(b, '\n'), 0644)
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRetryCounterMiddleware(t *testing.T) {
+	counter := &retryCounter{}
+	client := s3.New(s3.Options{
+		Region:      "eu-west-1",
+		Credentials: aws.AnonymousCredentials{},
+		HTTPClient:  &statusClient{statuses: []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable, http.StatusOK, http.StatusOK}},
+		Retryer: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.Backoff = retry.BackoffDelayerFunc(func(int, error) (time.Duration, error) { return 0, nil })
+		}),
+		APIOptions: []func(*middleware.Stack) error{counter.addMiddleware},
+	})
+
+	for i := 0; i < 2; i++ {
+		_, err := client.HeadObject(context.Background(), &s3.HeadObjectInput{
+			Bucket: aws.String("mybucket"),
+			Key:    aws.String("my/key"),
+		})
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, int64(2), counter.count.Load())
+}
+
+func TestRunSummaryString(t *testing.T) {
+	cases := []struct {
+		name        string
+		interrupted bool
+		want        string
+	}{
+		{"finished", false, "Summary: 3 completed, 1 failed, 2 skipped, 2.0 MiB in 2s (1.0 MiB/s), 4 retries"},
+		{"interrupted", true, "Interrupted: 3 completed, 1 failed, 2 skipped, 2.0 MiB in 2s (1.0 MiB/s), 4 retries"},
+	}
+
+	result := s3transfer.Result{Completed: 3, Failed: 1, Skipped: 2, Bytes: 2 * 1024 * 1024}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			summary := newRunSummary("cp", result, 2*time.Second, 4, nil)
+			summary.Interrupted = c.interrupted
+
+			got := summary.String()
+			if got != c.want {
+				t.Errorf("got %q want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestWriteReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	summary := newRunSummary("rm", s3transfer.Result{Completed: 5, Failed: 1}, time.Second, 0, errors.New("access denied"))
+	require.NoError(t, writeReport(path, summary))
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	var got runSummary
+	require.NoError(t, json.Unmarshal(b, &got))
+	require.Equal(t, summary, got)
+}
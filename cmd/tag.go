@@ -0,0 +1,184 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+	"github.com/spf13/cobra"
+)
+
+// tagCmd represents the tag command
+var tagCmd = &cobra.Command{
+	Use:   "tag",
+	Short: "Get and change tags of S3 objects",
+}
+
+var tagGetCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Print tags of objects",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		getTags(cmd.Context(), args)
+	},
+}
+
+var tagSetCmd = &cobra.Command{
+	Use:   "set",
+	Short: "Replace tags of objects",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tagS3(cmd.Context(), args, s3transfer.TagSet)
+	},
+}
+
+var tagAddCmd = &cobra.Command{
+	Use:   "add",
+	Short: "Add tags to objects, replacing values of existing keys",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tagS3(cmd.Context(), args, s3transfer.TagAdd)
+	},
+}
+
+var tagDeleteCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete tags of objects by key, all tags when no tag is given",
+	Args:  cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		tagS3(cmd.Context(), args, s3transfer.TagDelete)
+	},
+}
+
+var globalTags []string
+
+func init() {
+	rootCmd.AddCommand(tagCmd)
+	tagCmd.AddCommand(tagGetCmd, tagSetCmd, tagAddCmd, tagDeleteCmd)
+	tagSetCmd.Flags().StringArrayVar(&globalTags, "tag", nil, "tag as key=value, can be repeated")
+	tagAddCmd.Flags().StringArrayVar(&globalTags, "tag", nil, "tag as key=value, can be repeated")
+	tagDeleteCmd.Flags().StringArrayVar(&globalTags, "tag", nil, "key of tag to delete, can be repeated")
+	tagSetCmd.MarkFlagRequired("tag")
+	tagAddCmd.MarkFlagRequired("tag")
+}
+
+// parse repeated key=value tag flags, values are ignored when keysOnly is set
+func parseTags(values []string, keysOnly bool) (map[string]string, error) {
+	tags := make(map[string]string, len(values))
+	for _, v := range values {
+		k, val, ok := strings.Cut(v, "=")
+		if len(k) == 0 || (!ok && !keysOnly) {
+			return nil, fmt.Errorf("tag should be key=value, got %q", v)
+		}
+		tags[k] = val
+	}
+	return tags, nil
+}
+
+func tagS3(ctx context.Context, paths []string, action s3transfer.TagAction) {
+	tags, err := parseTags(globalTags, action == s3transfer.TagDelete)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+
+	var total s3transfer.Result
+	var firstErr error
+	start := time.Now()
+	for alias, aliasPaths := range groupByAlias(paths) {
+		result, err := tagS3WithAlias(ctx, alias, aliasPaths, action, tags)
+		total.Add(result)
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	reportRun(ctx, os.Stderr, "tag", total, start, firstErr)
+}
+
+func tagS3WithAlias(ctx context.Context, alias string, paths []string, action s3transfer.TagAction, tags map[string]string) (s3transfer.Result, error) {
+	opts, err := newTransferOptions()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
+		return s3transfer.Result{}, err
+	}
+
+	printer, err := newEventPrinter(globalOutput, nil)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return s3transfer.Result{}, err
+	}
+	opts.OnEvent = printer.print
+
+	client, err := newClient(ctx, alias, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
+		return s3transfer.Result{}, err
+	}
+
+	result, err := client.Tag(ctx, stripAliases(paths), action, tags)
+	if err != nil && !printer.reported(err) {
+		fmt.Fprintln(os.Stderr, "Error while tagging objects", err)
+	}
+	return result, err
+}
+
+func getTags(ctx context.Context, paths []string) {
+	if globalOutput != "text" && globalOutput != "json" {
+		fmt.Fprintf(os.Stderr, "output: unknown format %q, should be text or json\n", globalOutput)
+		return
+	}
+
+	for alias, aliasPaths := range groupByAlias(paths) {
+		opts, err := newTransferOptions()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
+			return
+		}
+
+		client, err := newClient(ctx, alias, opts)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Cannot create s3 client", err)
+			return
+		}
+
+		tags, err := client.Tags(ctx, stripAliases(aliasPaths))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error while reading tags", err)
+			return
+		}
+		printObjectTags(os.Stdout, tags, globalOutput == "json")
+	}
+}
+
+// objectTagsRecord is the JSON representation of tags of an object
+type objectTagsRecord struct {
+	Path string            `json:"path"`
+	Tags map[string]string `json:"tags"`
+}
+
+func printObjectTags(w io.Writer, tags []s3transfer.ObjectTags, asJSON bool) {
+	for _, t := range tags {
+		if asJSON {
+			// record has no values that cannot be marshalled
+			b, _ := json.Marshal(objectTagsRecord{Path: t.Path, Tags: t.Tags})
+			fmt.Fprintln(w, string(b))
+			continue
+		}
+
+		// tags are printed in the format --tagging takes, url.Values sorts them by key
+		values := make(url.Values, len(t.Tags))
+		for k, v := range t.Tags {
+			values.Set(k, v)
+		}
+		fmt.Fprintf(w, "%s\t%s\n", t.Path, values.Encode())
+	}
+}
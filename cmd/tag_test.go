@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/kullanici0606/s3cli/v2/pkg/s3transfer"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTags(t *testing.T) {
+	got, err := parseTags([]string{"env=prod", "note=a=b", "empty="}, false)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"env": "prod", "note": "a=b", "empty": ""}, got)
+
+	got, err = parseTags([]string{"env", "team=web"}, true)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"env": "", "team": "web"}, got)
+
+	for _, input := range []string{"env", "=prod"} {
+		_, err := parseTags([]string{input}, false)
+		require.Error(t, err, input)
+	}
+}
+
+func TestParseTagFilters(t *testing.T) {
+	got, err := parseTagFilters([]string{"env=prod", "team", "empty="})
+	require.NoError(t, err)
+	require.Equal(t, []s3transfer.TagFilter{
+		{Key: "env", Value: "prod"},
+		{Key: "team", AnyValue: true},
+		{Key: "empty"},
+	}, got)
+
+	_, err = parseTagFilters([]string{"=prod"})
+	require.Error(t, err)
+}
+
+func TestPrintObjectTags(t *testing.T) {
+	tags := []s3transfer.ObjectTags{
+		{Path: "s3://b/a", Tags: map[string]string{"team": "web", "env": "prod & dev"}},
+		{Path: "s3://b/b", Tags: map[string]string{}},
+	}
+
+	var out bytes.Buffer
+	printObjectTags(&out, tags, false)
+	want := "s3://b/a\tenv=prod+%26+dev&team=web\ns3://b/b\t\n"
+	if out.String() != want {
+		t.Errorf("got %q want %q", out.String(), want)
+	}
+
+	out.Reset()
+	printObjectTags(&out, tags[:1], true)
+	require.JSONEq(t, `{"path":"s3://b/a","tags":{"env":"prod & dev","team":"web"}}`, out.String())
+}
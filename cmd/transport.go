@@ -0,0 +1,134 @@
+/*
+Copyright © 2024 NAME HERE <EMAIL ADDRESS>
+*/
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+)
+
+// transportOptions holds settings of the HTTP client used for talking to S3, zero values keep SDK defaults
+type transportOptions struct {
+	caBundle              string
+	insecureSkipVerify    bool
+	proxy                 string
+	connectTimeout        time.Duration
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration
+	idleConnTimeout       time.Duration
+	maxIdleConns          int
+	maxConnsPerHost       int
+}
+
+// build HTTP client for SDK, returns nil when SDK defaults can be used as is
+func (o transportOptions) httpClient() (*awshttp.BuildableClient, error) {
+	if o == (transportOptions{}) {
+		return nil, nil
+	}
+
+	var rootCAs *x509.CertPool
+	if len(o.caBundle) != 0 {
+		pem, err := os.ReadFile(o.caBundle)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read ca bundle %w", err)
+		}
+
+		// internal CAs are added to system ones, so that public endpoints keep working
+		rootCAs, err = x509.SystemCertPool()
+		if err != nil {
+			rootCAs = x509.NewCertPool()
+		}
+
+		if !rootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in ca bundle %s", o.caBundle)
+		}
+	}
+
+	var proxy *url.URL
+	if len(o.proxy) != 0 {
+		var err error
+		proxy, err = url.Parse(o.proxy)
+		if err != nil || len(proxy.Host) == 0 {
+			return nil, fmt.Errorf("invalid proxy url %q", o.proxy)
+		}
+	}
+
+	client := awshttp.NewBuildableClient().WithTransportOptions(func(tr *http.Transport) {
+		if rootCAs != nil || o.insecureSkipVerify {
+			if tr.TLSClientConfig == nil {
+				tr.TLSClientConfig = &tls.Config{}
+			}
+			tr.TLSClientConfig.RootCAs = rootCAs
+			tr.TLSClientConfig.InsecureSkipVerify = o.insecureSkipVerify
+		}
+
+		if proxy != nil {
+			tr.Proxy = http.ProxyURL(proxy)
+		}
+
+		setIfNotZero(&tr.TLSHandshakeTimeout, o.tlsHandshakeTimeout)
+		setIfNotZero(&tr.ResponseHeaderTimeout, o.responseHeaderTimeout)
+		setIfNotZero(&tr.IdleConnTimeout, o.idleConnTimeout)
+		setIfNotZero(&tr.MaxIdleConns, o.maxIdleConns)
+		setIfNotZero(&tr.MaxIdleConnsPerHost, o.maxIdleConns)
+		setIfNotZero(&tr.MaxConnsPerHost, o.maxConnsPerHost)
+	})
+
+	if o.connectTimeout != 0 {
+		client = client.WithDialerOptions(func(d *net.Dialer) {
+			d.Timeout = o.connectTimeout
+		})
+	}
+
+	return client, nil
+}
+
+func setIfNotZero[T comparable](target *T, value T) {
+	var zero T
+	if value != zero {
+		*target = value
+	}
+}
+
+// optionalBoolFlag is a boolean flag that sets a shared *bool, used for pairs of flags like --path-style and --virtual-host
+type optionalBoolFlag struct {
+	target **bool
+	value  bool
+}
+
+func (f optionalBoolFlag) String() string {
+	if *f.target == nil {
+		return "false"
+	}
+	return strconv.FormatBool(**f.target == f.value)
+}
+
+func (f optionalBoolFlag) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+
+	// --path-style=false means the other flag of the pair
+	v := f.value == b
+	*f.target = &v
+	return nil
+}
+
+func (f optionalBoolFlag) Type() string {
+	return "bool"
+}
+
+func (f optionalBoolFlag) IsBoolFlag() bool {
+	return true
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTransportOptionsDefaults(t *testing.T) {
+	client, err := transportOptions{}.httpClient()
+	require.NoError(t, err)
+	require.Nil(t, client, "SDK default client expected")
+}
+
+func TestTransportOptionsHTTPClient(t *testing.T) {
+	opts := transportOptions{
+		insecureSkipVerify:    true,
+		proxy:                 "http://proxy.local:3128",
+		connectTimeout:        3 * time.Second,
+		responseHeaderTimeout: 10 * time.Second,
+		maxIdleConns:          50,
+	}
+
+	client, err := opts.httpClient()
+	require.NoError(t, err)
+
+	tr := client.GetTransport()
+	require.True(t, tr.TLSClientConfig.InsecureSkipVerify)
+	require.Equal(t, 10*time.Second, tr.ResponseHeaderTimeout)
+	require.Equal(t, 50, tr.MaxIdleConns)
+	require.Equal(t, 50, tr.MaxIdleConnsPerHost)
+	require.Equal(t, 3*time.Second, client.GetDialer().Timeout)
+
+	proxy, err := tr.Proxy(&http.Request{URL: &url.URL{Scheme: "https", Host: "s3.amazonaws.com"}})
+	require.NoError(t, err)
+	require.Equal(t, "proxy.local:3128", proxy.Host)
+}
+
+func TestTransportOptionsForError(t *testing.T) {
+	_, err := transportOptions{caBundle: "/nonexistent/ca.pem"}.httpClient()
+	require.Error(t, err)
+
+	_, err = transportOptions{caBundle: writeTestConfig(t, "not a certificate")}.httpClient()
+	require.Error(t, err)
+
+	_, err = transportOptions{proxy: "not a url"}.httpClient()
+	require.Error(t, err)
+}
+
+func TestOptionalBoolFlag(t *testing.T) {
+	var pathStyle *bool
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.VarPF(optionalBoolFlag{target: &pathStyle, value: true}, "path-style", "", "").NoOptDefVal = "true"
+	flags.VarPF(optionalBoolFlag{target: &pathStyle, value: false}, "virtual-host", "", "").NoOptDefVal = "true"
+
+	require.NoError(t, flags.Parse(nil))
+	require.Nil(t, pathStyle)
+
+	require.NoError(t, flags.Parse([]string{"--virtual-host"}))
+	require.NotNil(t, pathStyle)
+	require.False(t, *pathStyle)
+
+	require.NoError(t, flags.Parse([]string{"--path-style"}))
+	require.True(t, *pathStyle)
+
+	// false selects the other flag of the pair
+	require.NoError(t, flags.Parse([]string{"--path-style=false"}))
+	require.False(t, *pathStyle)
+
+	require.NoError(t, flags.Parse([]string{"--virtual-host=false"}))
+	require.True(t, *pathStyle)
+}
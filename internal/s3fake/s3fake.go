@@ -0,0 +1,1385 @@
+// Package s3fake provides an in-memory S3 stand-in implementing the subset of S3 API used by s3cli,
+// so that commands can be tested offline. It supports list pagination, delimiters, multipart uploads,
+// versioned buckets, archived objects and their restores, conditional writes, checksums, injected errors and
+// records calls for checking concurrency.
+package s3fake
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+const defaultPageSize = 1000
+
+// attributes given when an object is written
+type attributes struct {
+	metadata           map[string]string
+	contentType        string
+	contentEncoding    string
+	cacheControl       string
+	contentDisposition string
+	expires            *time.Time
+	sse                types.ServerSideEncryption
+	kmsKeyID           string
+	customerKeyMD5     string
+	storageClass       types.StorageClass
+	acl                types.ObjectCannedACL
+	tags               map[string]string
+	checksumAlgorithm  types.ChecksumAlgorithm
+	// base64 checksum, composite checksums of multipart uploads end with -<number of parts>
+	checksum string
+}
+
+type object struct {
+	attributes
+	data         []byte
+	versionID    string
+	etag         string
+	lastModified time.Time
+	deleteMarker bool
+	// sizes and checksums of parts of objects uploaded in parts with checksums
+	partSizes     []int64
+	partChecksums []string
+
+	// restore of archived objects, ready at restoreReady and expiring at restoreExpiry
+	restoreRequested bool
+	restoreReady     time.Time
+	restoreExpiry    time.Time
+}
+
+type bucket struct {
+	versioning bool
+	// versions of keys, latest version is the last one
+	versions map[string][]*object
+}
+
+// latest version of key, nil if key does not exist or is deleted
+func (b *bucket) latest(key string) *object {
+	versions := b.versions[key]
+	if len(versions) == 0 {
+		return nil
+	}
+
+	o := versions[len(versions)-1]
+	if o.deleteMarker {
+		return nil
+	}
+	return o
+}
+
+func (b *bucket) version(key, versionID string) *object {
+	for _, o := range b.versions[key] {
+		if o.versionID == versionID && !o.deleteMarker {
+			return o
+		}
+	}
+	return nil
+}
+
+type upload struct {
+	attributes
+	bucket        string
+	key           string
+	parts         map[int32][]byte
+	partChecksums map[int32]string
+}
+
+// Client is an in-memory S3. Its zero value is not usable, create it with New.
+type Client struct {
+	// number of keys returned in a single list page, defaults to 1000
+	PageSize int32
+	// latency added to every call, useful for observing concurrency
+	Latency time.Duration
+	// wall clock time restores of archived objects take, 0 restores them immediately
+	RestoreDuration time.Duration
+
+	mu           sync.Mutex
+	buckets      map[string]*bucket
+	uploads      map[string]*upload
+	nextID       int
+	now          time.Time
+	failures     map[string]error
+	deleteErrors map[string]types.Error
+	calls        map[string]int
+	inFlight     int
+	maxInFlight  int
+}
+
+func New() *Client {
+	return &Client{
+		buckets:      make(map[string]*bucket),
+		uploads:      make(map[string]*upload),
+		now:          time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		failures:     make(map[string]error),
+		deleteErrors: make(map[string]types.Error),
+		calls:        make(map[string]int),
+	}
+}
+
+// CreateBucket creates an empty bucket, versioning is enabled when versioned is true
+func (c *Client) CreateBucket(name string, versioned bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.buckets[name] = &bucket{versioning: versioned, versions: make(map[string][]*object)}
+}
+
+// Put stores data under bucket/key creating the bucket if needed
+func (c *Client) Put(bucketName, key string, data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.buckets[bucketName]
+	if !ok {
+		b = &bucket{versions: make(map[string][]*object)}
+		c.buckets[bucketName] = b
+	}
+	c.store(b, key, data, attributes{})
+}
+
+// Get returns latest contents of bucket/key
+func (c *Client) Get(bucketName, key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.buckets[bucketName]
+	if !ok {
+		return nil, false
+	}
+
+	o := b.latest(key)
+	if o == nil {
+		return nil, false
+	}
+	return o.data, true
+}
+
+// Metadata returns user metadata of latest version of bucket/key
+func (c *Client) Metadata(bucketName, key string) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.buckets[bucketName]
+	if !ok {
+		return nil
+	}
+
+	o := b.latest(key)
+	if o == nil {
+		return nil
+	}
+	return o.metadata
+}
+
+// Tags returns tags of latest version of bucket/key
+func (c *Client) Tags(bucketName, key string) map[string]string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.buckets[bucketName]
+	if !ok {
+		return nil
+	}
+
+	o := b.latest(key)
+	if o == nil {
+		return nil
+	}
+	return o.tags
+}
+
+// ACL returns canned ACL of latest version of bucket/key was written with
+func (c *Client) ACL(bucketName, key string) types.ObjectCannedACL {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.buckets[bucketName]
+	if !ok {
+		return ""
+	}
+
+	o := b.latest(key)
+	if o == nil {
+		return ""
+	}
+	return o.acl
+}
+
+// Keys returns sorted keys existing in bucket
+func (c *Client) Keys(bucketName string) []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, ok := c.buckets[bucketName]
+	if !ok {
+		return nil
+	}
+	return b.keys("")
+}
+
+// FailOperation makes all following calls of operation, i.e. GetObject, return err
+func (c *Client) FailOperation(operation string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failures[operation] = err
+}
+
+// FailDelete makes DeleteObjects report an error for key instead of deleting it
+func (c *Client) FailDelete(key, code, message string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.deleteErrors[key] = types.Error{Key: aws.String(key), Code: aws.String(code), Message: aws.String(message)}
+}
+
+// Calls returns number of calls made to operation
+func (c *Client) Calls(operation string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[operation]
+}
+
+// MaxInFlight returns maximum number of calls that were running concurrently
+func (c *Client) MaxInFlight() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.maxInFlight
+}
+
+// PendingUploads returns number of multipart uploads neither completed nor aborted
+func (c *Client) PendingUploads() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.uploads)
+}
+
+// record call of operation, returns injected error if any. Returned func should be called when the call is done.
+func (c *Client) begin(ctx context.Context, operation string) (func(), error) {
+	c.mu.Lock()
+	c.calls[operation]++
+	c.inFlight++
+	c.maxInFlight = max(c.maxInFlight, c.inFlight)
+	err := c.failures[operation]
+	c.mu.Unlock()
+
+	done := func() {
+		c.mu.Lock()
+		c.inFlight--
+		c.mu.Unlock()
+	}
+
+	if c.Latency > 0 {
+		select {
+		case <-time.After(c.Latency):
+		case <-ctx.Done():
+			return done, ctx.Err()
+		}
+	}
+
+	if err == nil {
+		err = ctx.Err()
+	}
+	return done, err
+}
+
+func (c *Client) bucket(name string) (*bucket, error) {
+	b, ok := c.buckets[name]
+	if !ok {
+		return nil, &types.NoSuchBucket{Message: aws.String("bucket " + name + " does not exist")}
+	}
+	return b, nil
+}
+
+func (c *Client) id() string {
+	c.nextID++
+	return fmt.Sprintf("%08d", c.nextID)
+}
+
+// store new version of key, must be called with mu held
+func (c *Client) store(b *bucket, key string, data []byte, attrs attributes) *object {
+	sum := md5.Sum(data)
+	c.now = c.now.Add(time.Second)
+	o := &object{
+		data:         data,
+		etag:         `"` + hex.EncodeToString(sum[:]) + `"`,
+		lastModified: c.now,
+		attributes:   attrs,
+	}
+
+	if !b.versioning {
+		o.versionID = "null"
+		b.versions[key] = []*object{o}
+		return o
+	}
+
+	o.versionID = c.id()
+	b.versions[key] = append(b.versions[key], o)
+	return o
+}
+
+// base64 checksum of data, empty for unknown algorithms
+func checksumOf(algorithm types.ChecksumAlgorithm, data []byte) string {
+	var h hash.Hash
+	switch algorithm {
+	case types.ChecksumAlgorithmCrc32:
+		h = crc32.NewIEEE()
+	case types.ChecksumAlgorithmCrc32c:
+		h = crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case types.ChecksumAlgorithmSha1:
+		h = sha1.New()
+	case types.ChecksumAlgorithmSha256:
+		h = sha256.New()
+	default:
+		return ""
+	}
+	h.Write(data)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// checksum given in a request, computed for data when only its algorithm is given as the SDK does
+func requestChecksum(algorithm types.ChecksumAlgorithm, data []byte, crc32, crc32c, sha1, sha256 *string) (types.ChecksumAlgorithm, string, error) {
+	for _, f := range []struct {
+		algorithm types.ChecksumAlgorithm
+		value     *string
+	}{
+		{types.ChecksumAlgorithmCrc32, crc32},
+		{types.ChecksumAlgorithmCrc32c, crc32c},
+		{types.ChecksumAlgorithmSha1, sha1},
+		{types.ChecksumAlgorithmSha256, sha256},
+	} {
+		if f.value == nil {
+			continue
+		}
+		if checksumOf(f.algorithm, data) != *f.value {
+			return "", "", &smithy.GenericAPIError{Code: "BadDigest", Message: "The " + string(f.algorithm) + " you specified did not match the calculated checksum."}
+		}
+		return f.algorithm, *f.value, nil
+	}
+	return algorithm, checksumOf(algorithm, data), nil
+}
+
+// checksum response fields, only the one of algorithm is set
+func checksumFields(algorithm types.ChecksumAlgorithm, checksum string) (crc32, crc32c, sha1, sha256 *string) {
+	switch algorithm {
+	case types.ChecksumAlgorithmCrc32:
+		crc32 = aws.String(checksum)
+	case types.ChecksumAlgorithmCrc32c:
+		crc32c = aws.String(checksum)
+	case types.ChecksumAlgorithmSha1:
+		sha1 = aws.String(checksum)
+	case types.ChecksumAlgorithmSha256:
+		sha256 = aws.String(checksum)
+	}
+	return crc32, crc32c, sha1, sha256
+}
+
+// headers optFns add to requests, conditional write headers have no fields in SDK inputs
+func requestHeaders(ctx context.Context, optFns []func(*s3.Options)) (http.Header, error) {
+	var opts s3.Options
+	for _, fn := range optFns {
+		fn(&opts)
+	}
+
+	stack := middleware.NewStack("s3fake", smithyhttp.NewStackRequest)
+	for _, fn := range opts.APIOptions {
+		if err := fn(stack); err != nil {
+			return nil, err
+		}
+	}
+
+	header := http.Header{}
+	send := middleware.HandlerFunc(func(ctx context.Context, input interface{}) (interface{}, middleware.Metadata, error) {
+		if req, ok := input.(*smithyhttp.Request); ok {
+			header = req.Header
+		}
+		return nil, middleware.Metadata{}, nil
+	})
+	_, _, err := middleware.DecorateHandler(send, stack).Handle(ctx, struct{}{})
+	return header, err
+}
+
+var errPreconditionFailed = &smithy.GenericAPIError{Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold"}
+
+// check If-None-Match and If-Match conditions of writing key
+func checkWriteConditions(b *bucket, key string, header http.Header) error {
+	o := b.latest(key)
+	if header.Get("If-None-Match") == "*" && o != nil {
+		return errPreconditionFailed
+	}
+	if etag := header.Get("If-Match"); len(etag) != 0 {
+		if o == nil {
+			return &types.NoSuchKey{Message: aws.String("key " + key + " does not exist")}
+		}
+		if etag != o.etag {
+			return errPreconditionFailed
+		}
+	}
+	return nil
+}
+
+// objects in archive storage classes can only be read after a restore
+func (o *object) archived() bool {
+	if o.storageClass != types.StorageClassGlacier && o.storageClass != types.StorageClassDeepArchive {
+		return false
+	}
+	return !o.restoreRequested || time.Now().Before(o.restoreReady)
+}
+
+// source object of a copy given as url encoded bucket/key, read with the given SSE-C key when its etag matches
+func (c *Client) copySource(source, keyMD5, ifMatch *string) (*object, error) {
+	srcBucket, srcKey, found := strings.Cut(aws.ToString(source), "/")
+	if !found {
+		return nil, fmt.Errorf("invalid copy source %s", aws.ToString(source))
+	}
+
+	srcKey, err := url.PathUnescape(srcKey)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := c.lookup(srcBucket, srcKey, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCustomerKey(src, keyMD5); err != nil {
+		return nil, err
+	}
+
+	if err := checkArchived(src); err != nil {
+		return nil, err
+	}
+
+	if etag := aws.ToString(ifMatch); len(etag) != 0 && etag != src.etag {
+		return nil, errPreconditionFailed
+	}
+	return src, nil
+}
+
+func checkArchived(o *object) error {
+	if o.archived() {
+		return &types.InvalidObjectState{Message: aws.String("The operation is not valid for the object's storage class"), StorageClass: o.storageClass}
+	}
+	return nil
+}
+
+// x-amz-restore header of object, nil when no restore was requested
+func (o *object) restoreHeader() *string {
+	if !o.restoreRequested {
+		return nil
+	}
+	if time.Now().Before(o.restoreReady) {
+		return aws.String(`ongoing-request="true"`)
+	}
+	return aws.String(`ongoing-request="false", expiry-date="` + o.restoreExpiry.Format(http.TimeFormat) + `"`)
+}
+
+// algorithm of SSE-C objects, nil for others
+func (o *object) customerAlgorithm() *string {
+	if len(o.customerKeyMD5) == 0 {
+		return nil
+	}
+	return aws.String(string(types.ServerSideEncryptionAes256))
+}
+
+// S3 rejects reading SSE-C objects without their key and other objects with a key
+func checkCustomerKey(o *object, keyMD5 *string) error {
+	if o.customerKeyMD5 != aws.ToString(keyMD5) {
+		return &smithy.GenericAPIError{Code: "InvalidRequest", Message: "The object was stored using a form of Server Side Encryption. The correct parameters must be provided to retrieve the object."}
+	}
+	return nil
+}
+
+// tags of url query encoded tagging header
+func parseTagging(tagging *string) (map[string]string, error) {
+	if tagging == nil {
+		return nil, nil
+	}
+
+	values, err := url.ParseQuery(*tagging)
+	if err != nil {
+		return nil, &smithy.GenericAPIError{Code: "InvalidArgument", Message: "invalid tagging " + *tagging}
+	}
+
+	tags := make(map[string]string, len(values))
+	for k, v := range values {
+		tags[k] = v[0]
+	}
+	return tags, nil
+}
+
+// sorted keys with a latest version under prefix
+func (b *bucket) keys(prefix string) []string {
+	keys := make([]string, 0, len(b.versions))
+	for k := range b.versions {
+		if strings.HasPrefix(k, prefix) && b.latest(k) != nil {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (c *Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	done, err := c.begin(ctx, "ListObjectsV2")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := c.bucket(aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	pageSize := c.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+	if params.MaxKeys != nil && *params.MaxKeys < pageSize {
+		pageSize = *params.MaxKeys
+	}
+
+	prefix, delimiter := aws.ToString(params.Prefix), aws.ToString(params.Delimiter)
+	start := aws.ToString(params.ContinuationToken)
+	if start == "" {
+		start = aws.ToString(params.StartAfter)
+	}
+
+	output := &s3.ListObjectsV2Output{
+		Name:      params.Bucket,
+		Prefix:    params.Prefix,
+		Delimiter: params.Delimiter,
+	}
+
+	seenPrefixes := make(map[string]bool)
+	var count int32
+	var last string
+	for _, k := range b.keys(prefix) {
+		if k <= start {
+			continue
+		}
+
+		if count == pageSize {
+			output.IsTruncated = aws.Bool(true)
+			output.NextContinuationToken = aws.String(last)
+			break
+		}
+
+		if delimiter != "" {
+			if idx := strings.Index(k[len(prefix):], delimiter); idx != -1 {
+				commonPrefix := k[:len(prefix)+idx+len(delimiter)]
+				// 0xff never appears in utf-8 keys, so the next page starts after all keys under the common prefix
+				last = commonPrefix + "\xff"
+				if !seenPrefixes[commonPrefix] {
+					seenPrefixes[commonPrefix] = true
+					output.CommonPrefixes = append(output.CommonPrefixes, types.CommonPrefix{Prefix: aws.String(commonPrefix)})
+					count++
+				}
+				continue
+			}
+		}
+
+		o := b.latest(k)
+		output.Contents = append(output.Contents, types.Object{
+			Key:          aws.String(k),
+			Size:         aws.Int64(int64(len(o.data))),
+			ETag:         aws.String(o.etag),
+			LastModified: aws.Time(o.lastModified),
+			StorageClass: types.ObjectStorageClass(o.storageClass),
+		})
+		last = k
+		count++
+	}
+
+	if output.IsTruncated == nil {
+		output.IsTruncated = aws.Bool(false)
+	}
+	output.KeyCount = aws.Int32(count)
+	return output, nil
+}
+
+func (c *Client) ListObjectVersions(ctx context.Context, params *s3.ListObjectVersionsInput, optFns ...func(*s3.Options)) (*s3.ListObjectVersionsOutput, error) {
+	done, err := c.begin(ctx, "ListObjectVersions")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := c.bucket(aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(b.versions))
+	for k := range b.versions {
+		if strings.HasPrefix(k, aws.ToString(params.Prefix)) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	// all versions are returned in a single page
+	output := &s3.ListObjectVersionsOutput{Name: params.Bucket, Prefix: params.Prefix, IsTruncated: aws.Bool(false)}
+	for _, k := range keys {
+		versions := b.versions[k]
+		for i := len(versions) - 1; i >= 0; i-- {
+			o := versions[i]
+			isLatest := aws.Bool(i == len(versions)-1)
+			if o.deleteMarker {
+				output.DeleteMarkers = append(output.DeleteMarkers, types.DeleteMarkerEntry{
+					Key:          aws.String(k),
+					VersionId:    aws.String(o.versionID),
+					IsLatest:     isLatest,
+					LastModified: aws.Time(o.lastModified),
+				})
+				continue
+			}
+
+			output.Versions = append(output.Versions, types.ObjectVersion{
+				Key:          aws.String(k),
+				VersionId:    aws.String(o.versionID),
+				IsLatest:     isLatest,
+				Size:         aws.Int64(int64(len(o.data))),
+				ETag:         aws.String(o.etag),
+				LastModified: aws.Time(o.lastModified),
+			})
+		}
+	}
+	return output, nil
+}
+
+// find object for get and head requests
+func (c *Client) lookup(bucketName, key, versionID string) (*object, error) {
+	b, err := c.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+
+	var o *object
+	if versionID != "" {
+		o = b.version(key, versionID)
+	} else {
+		o = b.latest(key)
+	}
+
+	if o == nil {
+		return nil, &types.NoSuchKey{Message: aws.String("key " + key + " does not exist")}
+	}
+	return o, nil
+}
+
+func (c *Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	done, err := c.begin(ctx, "HeadObject")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, err := c.lookup(aws.ToString(params.Bucket), aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if err != nil {
+		// HEAD responses have no body, so S3 reports missing keys as NotFound
+		if _, ok := err.(*types.NoSuchKey); ok {
+			return nil, &types.NotFound{}
+		}
+		return nil, err
+	}
+
+	if err := checkCustomerKey(o, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+
+	output := &s3.HeadObjectOutput{
+		ContentLength:        aws.Int64(int64(len(o.data))),
+		ETag:                 aws.String(o.etag),
+		LastModified:         aws.Time(o.lastModified),
+		VersionId:            aws.String(o.versionID),
+		Metadata:             o.metadata,
+		ContentType:          stringOrNil(o.contentType),
+		ContentEncoding:      stringOrNil(o.contentEncoding),
+		CacheControl:         stringOrNil(o.cacheControl),
+		ContentDisposition:   stringOrNil(o.contentDisposition),
+		Expires:              o.expires,
+		ServerSideEncryption: o.sse,
+		SSEKMSKeyId:          stringOrNil(o.kmsKeyID),
+		SSECustomerAlgorithm: o.customerAlgorithm(),
+		SSECustomerKeyMD5:    stringOrNil(o.customerKeyMD5),
+		StorageClass:         o.storageClass,
+		Restore:              o.restoreHeader(),
+	}
+	if params.ChecksumMode == types.ChecksumModeEnabled {
+		output.ChecksumCRC32, output.ChecksumCRC32C, output.ChecksumSHA1, output.ChecksumSHA256 = checksumFields(o.checksumAlgorithm, o.checksum)
+	}
+	return output, nil
+}
+
+func (c *Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	done, err := c.begin(ctx, "GetObject")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, err := c.lookup(aws.ToString(params.Bucket), aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCustomerKey(o, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+
+	if err := checkArchived(o); err != nil {
+		return nil, err
+	}
+
+	if etag := aws.ToString(params.IfMatch); len(etag) != 0 && etag != o.etag {
+		return nil, errPreconditionFailed
+	}
+
+	data := o.data
+	if params.Range != nil {
+		var start, end int64
+		if _, err := fmt.Sscanf(aws.ToString(params.Range), "bytes=%d-%d", &start, &end); err != nil || start > end || start >= int64(len(data)) {
+			return nil, fmt.Errorf("invalid range %s", aws.ToString(params.Range))
+		}
+		data = data[start:min(end+1, int64(len(data)))]
+	}
+
+	output := &s3.GetObjectOutput{
+		Body:                 io.NopCloser(bytes.NewReader(data)),
+		ContentLength:        aws.Int64(int64(len(data))),
+		ETag:                 aws.String(o.etag),
+		LastModified:         aws.Time(o.lastModified),
+		VersionId:            aws.String(o.versionID),
+		Metadata:             o.metadata,
+		ContentType:          stringOrNil(o.contentType),
+		ContentEncoding:      stringOrNil(o.contentEncoding),
+		CacheControl:         stringOrNil(o.cacheControl),
+		ContentDisposition:   stringOrNil(o.contentDisposition),
+		Expires:              o.expires,
+		ServerSideEncryption: o.sse,
+		SSEKMSKeyId:          stringOrNil(o.kmsKeyID),
+		SSECustomerAlgorithm: o.customerAlgorithm(),
+		SSECustomerKeyMD5:    stringOrNil(o.customerKeyMD5),
+		StorageClass:         o.storageClass,
+	}
+	if len(o.tags) != 0 {
+		output.TagCount = aws.Int32(int32(len(o.tags)))
+	}
+	// checksums are of whole objects, so they are not returned for ranges
+	if params.ChecksumMode == types.ChecksumModeEnabled && params.Range == nil {
+		output.ChecksumCRC32, output.ChecksumCRC32C, output.ChecksumSHA1, output.ChecksumSHA256 = checksumFields(o.checksumAlgorithm, o.checksum)
+	}
+	return output, nil
+}
+
+func (c *Client) GetObjectAttributes(ctx context.Context, params *s3.GetObjectAttributesInput, optFns ...func(*s3.Options)) (*s3.GetObjectAttributesOutput, error) {
+	done, err := c.begin(ctx, "GetObjectAttributes")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, err := c.lookup(aws.ToString(params.Bucket), aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkCustomerKey(o, params.SSECustomerKeyMD5); err != nil {
+		return nil, err
+	}
+
+	output := &s3.GetObjectAttributesOutput{LastModified: aws.Time(o.lastModified), VersionId: aws.String(o.versionID)}
+	for _, a := range params.ObjectAttributes {
+		switch a {
+		case types.ObjectAttributesEtag:
+			output.ETag = aws.String(strings.Trim(o.etag, `"`))
+		case types.ObjectAttributesObjectSize:
+			output.ObjectSize = aws.Int64(int64(len(o.data)))
+		case types.ObjectAttributesStorageClass:
+			output.StorageClass = o.storageClass
+		case types.ObjectAttributesChecksum:
+			if len(o.checksumAlgorithm) != 0 {
+				checksum := &types.Checksum{}
+				checksum.ChecksumCRC32, checksum.ChecksumCRC32C, checksum.ChecksumSHA1, checksum.ChecksumSHA256 = checksumFields(o.checksumAlgorithm, o.checksum)
+				output.Checksum = checksum
+			}
+		case types.ObjectAttributesObjectParts:
+			output.ObjectParts = o.parts(params.PartNumberMarker, params.MaxParts)
+		}
+	}
+	return output, nil
+}
+
+// page of parts of objects uploaded in parts with checksums, nil for other objects
+func (o *object) parts(marker *string, maxParts *int32) *types.GetObjectAttributesParts {
+	if len(o.partSizes) == 0 {
+		return nil
+	}
+
+	start, _ := strconv.Atoi(aws.ToString(marker))
+	limit := int(aws.ToInt32(maxParts))
+	if limit <= 0 {
+		limit = 1000
+	}
+	end := min(start+limit, len(o.partSizes))
+
+	page := &types.GetObjectAttributesParts{
+		TotalPartsCount: aws.Int32(int32(len(o.partSizes))),
+		MaxParts:        aws.Int32(int32(limit)),
+		IsTruncated:     aws.Bool(end < len(o.partSizes)),
+	}
+	if end < len(o.partSizes) {
+		page.NextPartNumberMarker = aws.String(strconv.Itoa(end))
+	}
+	for i := start; i < end; i++ {
+		part := types.ObjectPart{PartNumber: aws.Int32(int32(i + 1)), Size: aws.Int64(o.partSizes[i])}
+		part.ChecksumCRC32, part.ChecksumCRC32C, part.ChecksumSHA1, part.ChecksumSHA256 = checksumFields(o.checksumAlgorithm, o.partChecksums[i])
+		page.Parts = append(page.Parts, part)
+	}
+	return page
+}
+
+func (c *Client) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	done, err := c.begin(ctx, "PutObject")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	if params.Body != nil {
+		data, err = io.ReadAll(params.Body)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := c.bucket(aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := parseTagging(params.Tagging)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := requestHeaders(ctx, optFns)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWriteConditions(b, aws.ToString(params.Key), header); err != nil {
+		return nil, err
+	}
+
+	algorithm, checksum, err := requestChecksum(params.ChecksumAlgorithm, data, params.ChecksumCRC32, params.ChecksumCRC32C, params.ChecksumSHA1, params.ChecksumSHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	o := c.store(b, aws.ToString(params.Key), data, attributes{
+		metadata:           params.Metadata,
+		contentType:        aws.ToString(params.ContentType),
+		contentEncoding:    aws.ToString(params.ContentEncoding),
+		cacheControl:       aws.ToString(params.CacheControl),
+		contentDisposition: aws.ToString(params.ContentDisposition),
+		expires:            params.Expires,
+		sse:                params.ServerSideEncryption,
+		kmsKeyID:           aws.ToString(params.SSEKMSKeyId),
+		customerKeyMD5:     aws.ToString(params.SSECustomerKeyMD5),
+		storageClass:       params.StorageClass,
+		acl:                params.ACL,
+		tags:               tags,
+		checksumAlgorithm:  algorithm,
+		checksum:           checksum,
+	})
+	return &s3.PutObjectOutput{ETag: aws.String(o.etag), VersionId: aws.String(o.versionID)}, nil
+}
+
+func (c *Client) CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	done, err := c.begin(ctx, "CopyObject")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	src, err := c.copySource(params.CopySource, params.CopySourceSSECustomerKeyMD5, params.CopySourceIfMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := c.bucket(aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	// headers and metadata are copied from the source unless replaced, encryption is always given by the request
+	attrs := src.attributes
+	if params.MetadataDirective == types.MetadataDirectiveReplace {
+		attrs = attributes{
+			metadata:           params.Metadata,
+			contentType:        aws.ToString(params.ContentType),
+			contentEncoding:    aws.ToString(params.ContentEncoding),
+			cacheControl:       aws.ToString(params.CacheControl),
+			contentDisposition: aws.ToString(params.ContentDisposition),
+			expires:            params.Expires,
+			tags:               src.tags,
+		}
+	}
+	attrs.sse = params.ServerSideEncryption
+	attrs.kmsKeyID = aws.ToString(params.SSEKMSKeyId)
+	attrs.customerKeyMD5 = aws.ToString(params.SSECustomerKeyMD5)
+	// storage class and ACL are not copied, tags are unless replaced
+	attrs.storageClass = params.StorageClass
+	attrs.acl = params.ACL
+	if params.TaggingDirective == types.TaggingDirectiveReplace {
+		if attrs.tags, err = parseTagging(params.Tagging); err != nil {
+			return nil, err
+		}
+	}
+	// copies have full object checksums of the requested algorithm or the algorithm of the source
+	attrs.checksumAlgorithm = src.checksumAlgorithm
+	if len(params.ChecksumAlgorithm) != 0 {
+		attrs.checksumAlgorithm = params.ChecksumAlgorithm
+	}
+	attrs.checksum = checksumOf(attrs.checksumAlgorithm, src.data)
+
+	o := c.store(b, aws.ToString(params.Key), src.data, attrs)
+	return &s3.CopyObjectOutput{
+		CopyObjectResult: &types.CopyObjectResult{ETag: aws.String(o.etag), LastModified: aws.Time(o.lastModified)},
+		VersionId:        aws.String(o.versionID),
+	}, nil
+}
+
+func (c *Client) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	done, err := c.begin(ctx, "RestoreObject")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, err := c.lookup(aws.ToString(params.Bucket), aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if err != nil {
+		return nil, err
+	}
+
+	if o.storageClass != types.StorageClassGlacier && o.storageClass != types.StorageClassDeepArchive {
+		return nil, &types.ObjectAlreadyInActiveTierError{Message: aws.String("Restore is not allowed for the object's current storage class")}
+	}
+
+	if o.restoreRequested && time.Now().Before(o.restoreReady) {
+		return nil, &smithy.GenericAPIError{Code: "RestoreAlreadyInProgress", Message: "Object restore is already in progress"}
+	}
+
+	var days int32 = 1
+	if params.RestoreRequest != nil && params.RestoreRequest.Days != nil {
+		days = *params.RestoreRequest.Days
+	}
+
+	// repeating a completed restore only extends its expiry
+	if !o.restoreRequested {
+		o.restoreRequested = true
+		o.restoreReady = time.Now().Add(c.RestoreDuration)
+	}
+	o.restoreExpiry = c.now.AddDate(0, 0, int(days))
+	return &s3.RestoreObjectOutput{}, nil
+}
+
+func (c *Client) GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error) {
+	done, err := c.begin(ctx, "GetObjectTagging")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, err := c.lookup(aws.ToString(params.Bucket), aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(o.tags))
+	for k := range o.tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	output := &s3.GetObjectTaggingOutput{TagSet: []types.Tag{}, VersionId: aws.String(o.versionID)}
+	for _, k := range keys {
+		output.TagSet = append(output.TagSet, types.Tag{Key: aws.String(k), Value: aws.String(o.tags[k])})
+	}
+	return output, nil
+}
+
+func (c *Client) PutObjectTagging(ctx context.Context, params *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error) {
+	done, err := c.begin(ctx, "PutObjectTagging")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, err := c.lookup(aws.ToString(params.Bucket), aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if err != nil {
+		return nil, err
+	}
+
+	// S3 allows at most 10 tags per object
+	if params.Tagging == nil || len(params.Tagging.TagSet) > 10 {
+		return nil, &smithy.GenericAPIError{Code: "BadRequest", Message: "Object tags cannot be greater than 10"}
+	}
+
+	tags := make(map[string]string, len(params.Tagging.TagSet))
+	for _, t := range params.Tagging.TagSet {
+		tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	o.tags = tags
+	return &s3.PutObjectTaggingOutput{VersionId: aws.String(o.versionID)}, nil
+}
+
+func (c *Client) DeleteObjectTagging(ctx context.Context, params *s3.DeleteObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectTaggingOutput, error) {
+	done, err := c.begin(ctx, "DeleteObjectTagging")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	o, err := c.lookup(aws.ToString(params.Bucket), aws.ToString(params.Key), aws.ToString(params.VersionId))
+	if err != nil {
+		return nil, err
+	}
+
+	o.tags = nil
+	return &s3.DeleteObjectTaggingOutput{VersionId: aws.String(o.versionID)}, nil
+}
+
+func (c *Client) DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	done, err := c.begin(ctx, "DeleteObjects")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	b, err := c.bucket(aws.ToString(params.Bucket))
+	if err != nil {
+		return nil, err
+	}
+
+	output := &s3.DeleteObjectsOutput{}
+	for _, id := range params.Delete.Objects {
+		key := aws.ToString(id.Key)
+		if e, ok := c.deleteErrors[key]; ok {
+			output.Errors = append(output.Errors, e)
+			continue
+		}
+
+		deleted := types.DeletedObject{Key: id.Key}
+		switch {
+		case id.VersionId != nil:
+			versions := b.versions[key]
+			for i, o := range versions {
+				if o.versionID == aws.ToString(id.VersionId) {
+					b.versions[key] = append(versions[:i:i], versions[i+1:]...)
+					break
+				}
+			}
+			deleted.VersionId = id.VersionId
+		case b.versioning:
+			c.now = c.now.Add(time.Second)
+			marker := &object{versionID: c.id(), deleteMarker: true, lastModified: c.now}
+			b.versions[key] = append(b.versions[key], marker)
+			deleted.DeleteMarker = aws.Bool(true)
+			deleted.DeleteMarkerVersionId = aws.String(marker.versionID)
+		default:
+			delete(b.versions, key)
+		}
+		output.Deleted = append(output.Deleted, deleted)
+	}
+	return output, nil
+}
+
+func (c *Client) CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error) {
+	done, err := c.begin(ctx, "CreateMultipartUpload")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.bucket(aws.ToString(params.Bucket)); err != nil {
+		return nil, err
+	}
+
+	tags, err := parseTagging(params.Tagging)
+	if err != nil {
+		return nil, err
+	}
+
+	id := c.id()
+	c.uploads[id] = &upload{
+		attributes: attributes{
+			metadata:           params.Metadata,
+			contentType:        aws.ToString(params.ContentType),
+			contentEncoding:    aws.ToString(params.ContentEncoding),
+			cacheControl:       aws.ToString(params.CacheControl),
+			contentDisposition: aws.ToString(params.ContentDisposition),
+			expires:            params.Expires,
+			sse:                params.ServerSideEncryption,
+			kmsKeyID:           aws.ToString(params.SSEKMSKeyId),
+			customerKeyMD5:     aws.ToString(params.SSECustomerKeyMD5),
+			storageClass:       params.StorageClass,
+			acl:                params.ACL,
+			tags:               tags,
+			checksumAlgorithm:  params.ChecksumAlgorithm,
+		},
+		bucket:        aws.ToString(params.Bucket),
+		key:           aws.ToString(params.Key),
+		parts:         make(map[int32][]byte),
+		partChecksums: make(map[int32]string),
+	}
+	return &s3.CreateMultipartUploadOutput{Bucket: params.Bucket, Key: params.Key, UploadId: aws.String(id)}, nil
+}
+
+func (c *Client) UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error) {
+	done, err := c.begin(ctx, "UploadPart")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	u, ok := c.uploads[aws.ToString(params.UploadId)]
+	if !ok {
+		return nil, &types.NoSuchUpload{}
+	}
+
+	if u.customerKeyMD5 != aws.ToString(params.SSECustomerKeyMD5) {
+		return nil, &smithy.GenericAPIError{Code: "InvalidRequest", Message: "The SSE-C key of the part does not match the key of the upload."}
+	}
+
+	algorithm, checksum, err := requestChecksum(params.ChecksumAlgorithm, data, params.ChecksumCRC32, params.ChecksumCRC32C, params.ChecksumSHA1, params.ChecksumSHA256)
+	if err != nil {
+		return nil, err
+	}
+	if algorithm != u.checksumAlgorithm {
+		return nil, &smithy.GenericAPIError{Code: "InvalidRequest", Message: "Checksum algorithm of the part does not match the algorithm of the upload."}
+	}
+
+	u.parts[aws.ToInt32(params.PartNumber)] = data
+	u.partChecksums[aws.ToInt32(params.PartNumber)] = checksum
+	sum := md5.Sum(data)
+	output := &s3.UploadPartOutput{ETag: aws.String(`"` + hex.EncodeToString(sum[:]) + `"`)}
+	// checksums computed by S3 are returned for completing the upload
+	if len(algorithm) != 0 {
+		output.ChecksumCRC32, output.ChecksumCRC32C, output.ChecksumSHA1, output.ChecksumSHA256 = checksumFields(algorithm, checksum)
+	}
+	return output, nil
+}
+
+func (c *Client) UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error) {
+	done, err := c.begin(ctx, "UploadPartCopy")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	src, err := c.copySource(params.CopySource, params.CopySourceSSECustomerKeyMD5, params.CopySourceIfMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	u, ok := c.uploads[aws.ToString(params.UploadId)]
+	if !ok {
+		return nil, &types.NoSuchUpload{}
+	}
+
+	if u.customerKeyMD5 != aws.ToString(params.SSECustomerKeyMD5) {
+		return nil, &smithy.GenericAPIError{Code: "InvalidRequest", Message: "The SSE-C key of the part does not match the key of the upload."}
+	}
+
+	data := src.data
+	if r := aws.ToString(params.CopySourceRange); len(r) != 0 {
+		var first, last int
+		if _, err := fmt.Sscanf(r, "bytes=%d-%d", &first, &last); err != nil || first > last || last >= len(src.data) {
+			return nil, &smithy.GenericAPIError{Code: "InvalidArgument", Message: "The x-amz-copy-source-range value must be of the form bytes=first-last where first and last are the zero-based offsets of the first and last bytes to copy"}
+		}
+		data = src.data[first : last+1]
+	}
+	data = append([]byte(nil), data...)
+
+	// checksums of copied parts are computed with the algorithm of the upload
+	checksum := checksumOf(u.checksumAlgorithm, data)
+	u.parts[aws.ToInt32(params.PartNumber)] = data
+	u.partChecksums[aws.ToInt32(params.PartNumber)] = checksum
+	sum := md5.Sum(data)
+	result := &types.CopyPartResult{ETag: aws.String(`"` + hex.EncodeToString(sum[:]) + `"`), LastModified: aws.Time(src.lastModified)}
+	if len(u.checksumAlgorithm) != 0 {
+		result.ChecksumCRC32, result.ChecksumCRC32C, result.ChecksumSHA1, result.ChecksumSHA256 = checksumFields(u.checksumAlgorithm, checksum)
+	}
+	return &s3.UploadPartCopyOutput{CopyPartResult: result}, nil
+}
+
+func (c *Client) CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error) {
+	done, err := c.begin(ctx, "CompleteMultipartUpload")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := aws.ToString(params.UploadId)
+	u, ok := c.uploads[id]
+	if !ok {
+		return nil, &types.NoSuchUpload{}
+	}
+
+	var data, partSums []byte
+	var partSizes []int64
+	var partChecksums []string
+	for _, p := range params.MultipartUpload.Parts {
+		part, ok := u.parts[aws.ToInt32(p.PartNumber)]
+		if !ok {
+			return nil, fmt.Errorf("part %d was not uploaded", aws.ToInt32(p.PartNumber))
+		}
+		data = append(data, part...)
+
+		if len(u.checksumAlgorithm) == 0 {
+			continue
+		}
+		// checksums of parts are required when the upload has a checksum algorithm
+		algorithm, checksum, err := requestChecksum("", part, p.ChecksumCRC32, p.ChecksumCRC32C, p.ChecksumSHA1, p.ChecksumSHA256)
+		if err != nil || algorithm != u.checksumAlgorithm {
+			return nil, &smithy.GenericAPIError{Code: "InvalidPart", Message: fmt.Sprintf("Checksum of part %d is missing or does not match.", aws.ToInt32(p.PartNumber))}
+		}
+		sum, _ := base64.StdEncoding.DecodeString(checksum)
+		partSums = append(partSums, sum...)
+		partSizes = append(partSizes, int64(len(part)))
+		partChecksums = append(partChecksums, checksum)
+	}
+
+	b, err := c.bucket(u.bucket)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := requestHeaders(ctx, optFns)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkWriteConditions(b, u.key, header); err != nil {
+		return nil, err
+	}
+
+	// checksum of multipart uploads is the checksum of checksums of their parts
+	attrs := u.attributes
+	if len(attrs.checksumAlgorithm) != 0 {
+		attrs.checksum = fmt.Sprintf("%s-%d", checksumOf(attrs.checksumAlgorithm, partSums), len(partChecksums))
+	}
+
+	delete(c.uploads, id)
+	o := c.store(b, u.key, data, attrs)
+	o.partSizes, o.partChecksums = partSizes, partChecksums
+	return &s3.CompleteMultipartUploadOutput{
+		Bucket:    aws.String(u.bucket),
+		Key:       aws.String(u.key),
+		ETag:      aws.String(o.etag),
+		VersionId: aws.String(o.versionID),
+	}, nil
+}
+
+func (c *Client) AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	done, err := c.begin(ctx, "AbortMultipartUpload")
+	defer done()
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	id := aws.ToString(params.UploadId)
+	if _, ok := c.uploads[id]; !ok {
+		return nil, &types.NoSuchUpload{}
+	}
+	delete(c.uploads, id)
+	return &s3.AbortMultipartUploadOutput{}, nil
+}
+
+// optional output field, nil when s is empty
+func stringOrNil(s string) *string {
+	if len(s) == 0 {
+		return nil
+	}
+	return aws.String(s)
+}
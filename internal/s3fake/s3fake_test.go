@@ -0,0 +1,151 @@
+package s3fake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestListObjectsV2Pagination(t *testing.T) {
+	c := New()
+	c.PageSize = 2
+	for _, k := range []string{"a", "b/1", "b/2", "c", "d"} {
+		c.Put("bucket", k, []byte(k))
+	}
+
+	cases := []struct {
+		name      string
+		delimiter *string
+		want      [][]string
+	}{
+		{"no delimiter", nil, [][]string{{"a", "b/1"}, {"b/2", "c"}, {"d"}}},
+		{"delimiter", aws.String("/"), [][]string{{"a", "b/"}, {"c", "d"}}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got [][]string
+			var token *string
+			for {
+				output, err := c.ListObjectsV2(context.Background(), &s3.ListObjectsV2Input{
+					Bucket:            aws.String("bucket"),
+					Delimiter:         tc.delimiter,
+					ContinuationToken: token,
+				})
+				require.NoError(t, err)
+
+				var page []string
+				for _, o := range output.Contents {
+					page = append(page, aws.ToString(o.Key))
+				}
+				for _, p := range output.CommonPrefixes {
+					page = append(page, aws.ToString(p.Prefix))
+				}
+				got = append(got, page)
+
+				if !aws.ToBool(output.IsTruncated) {
+					break
+				}
+				token = output.NextContinuationToken
+			}
+
+			require.ElementsMatch(t, tc.want, got)
+		})
+	}
+}
+
+func TestVersionedDelete(t *testing.T) {
+	ctx := context.Background()
+	c := New()
+	c.CreateBucket("bucket", true)
+	c.Put("bucket", "key", []byte("v1"))
+	c.Put("bucket", "key", []byte("v2"))
+
+	_, err := c.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String("bucket"),
+		Delete: &types.Delete{Objects: []types.ObjectIdentifier{{Key: aws.String("key")}}},
+	})
+	require.NoError(t, err)
+
+	_, ok := c.Get("bucket", "key")
+	require.False(t, ok)
+
+	versions, err := c.ListObjectVersions(ctx, &s3.ListObjectVersionsInput{Bucket: aws.String("bucket")})
+	require.NoError(t, err)
+	require.Len(t, versions.Versions, 2)
+	require.Len(t, versions.DeleteMarkers, 1)
+
+	output, err := c.GetObject(ctx, &s3.GetObjectInput{
+		Bucket:    aws.String("bucket"),
+		Key:       aws.String("key"),
+		VersionId: versions.Versions[1].VersionId,
+	})
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(output.Body)
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(data))
+}
+
+func TestMultipartUpload(t *testing.T) {
+	ctx := context.Background()
+	c := New()
+	c.CreateBucket("bucket", false)
+
+	upload, err := c.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{Bucket: aws.String("bucket"), Key: aws.String("key")})
+	require.NoError(t, err)
+	require.Equal(t, 1, c.PendingUploads())
+
+	var parts []types.CompletedPart
+	for i, p := range []string{"hello ", "world"} {
+		output, err := c.UploadPart(ctx, &s3.UploadPartInput{
+			UploadId:   upload.UploadId,
+			PartNumber: aws.Int32(int32(i + 1)),
+			Body:       bytes.NewReader([]byte(p)),
+		})
+		require.NoError(t, err)
+		parts = append(parts, types.CompletedPart{ETag: output.ETag, PartNumber: aws.Int32(int32(i + 1))})
+	}
+
+	_, err = c.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	require.NoError(t, err)
+	require.Equal(t, 0, c.PendingUploads())
+
+	data, ok := c.Get("bucket", "key")
+	require.True(t, ok)
+	require.Equal(t, "hello world", string(data))
+}
+
+func TestErrors(t *testing.T) {
+	ctx := context.Background()
+	c := New()
+	c.CreateBucket("bucket", false)
+
+	_, err := c.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("missing"), Key: aws.String("key")})
+	var noSuchBucket *types.NoSuchBucket
+	require.ErrorAs(t, err, &noSuchBucket)
+
+	_, err = c.GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String("bucket"), Key: aws.String("key")})
+	var noSuchKey *types.NoSuchKey
+	require.ErrorAs(t, err, &noSuchKey)
+
+	_, err = c.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("key")})
+	var notFound *types.NotFound
+	require.ErrorAs(t, err, &notFound)
+
+	injected := errors.New("injected")
+	c.FailOperation("PutObject", injected)
+	_, err = c.PutObject(ctx, &s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String("key")})
+	require.ErrorIs(t, err, injected)
+	require.Equal(t, 1, c.Calls("PutObject"))
+}
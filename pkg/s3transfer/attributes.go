@@ -0,0 +1,145 @@
+package s3transfer
+
+import (
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ObjectAttributes are the headers and user metadata objects are written with
+type ObjectAttributes struct {
+	ContentType        string
+	ContentEncoding    string
+	CacheControl       string
+	ContentDisposition string
+	Expires            *time.Time
+	Metadata           map[string]string
+	// url query encoded tags of the source of streamed copies, StorageOptions.Tagging takes precedence
+	tagging string
+}
+
+// attributes of an object read from S3, so that streamed copies keep them as CopyObject does
+func attributesOf(output *s3.GetObjectOutput) ObjectAttributes {
+	return ObjectAttributes{
+		ContentType:        aws.ToString(output.ContentType),
+		ContentEncoding:    aws.ToString(output.ContentEncoding),
+		CacheControl:       aws.ToString(output.CacheControl),
+		ContentDisposition: aws.ToString(output.ContentDisposition),
+		Expires:            output.Expires,
+		Metadata:           output.Metadata,
+	}
+}
+
+// attributes of an object headed in S3, so that multipart copies keep them as CopyObject does
+func headAttributes(output *s3.HeadObjectOutput) ObjectAttributes {
+	return ObjectAttributes{
+		ContentType:        aws.ToString(output.ContentType),
+		ContentEncoding:    aws.ToString(output.ContentEncoding),
+		CacheControl:       aws.ToString(output.CacheControl),
+		ContentDisposition: aws.ToString(output.ContentDisposition),
+		Expires:            output.Expires,
+		Metadata:           output.Metadata,
+	}
+}
+
+// attributes of file src at relative path rel uploaded to S3, content type is detected unless given or the
+// file is encrypted
+func (c *Client) uploadAttributes(src, rel string, f *os.File, encrypted bool) ObjectAttributes {
+	var attrs ObjectAttributes
+	if c.opts.Attributes != nil {
+		attrs = c.opts.Attributes(rel)
+	}
+
+	if len(attrs.ContentType) == 0 && !encrypted {
+		attrs.ContentType = detectContentType(src, f)
+	}
+	return attrs
+}
+
+// attributes of an object copied with REPLACE directive from source key at relative path rel with metadata,
+// client side encryption metadata is kept so that the copy can still be decrypted
+func (c *Client) copyAttributes(rel string, metadata map[string]string) ObjectAttributes {
+	var attrs ObjectAttributes
+	if c.opts.Attributes != nil {
+		attrs = c.opts.Attributes(rel)
+	}
+
+	if !isClientEncrypted(metadata) {
+		if len(attrs.ContentType) == 0 {
+			attrs.ContentType = mime.TypeByExtension(path.Ext(rel))
+		}
+		return attrs
+	}
+
+	cse := make(map[string]string)
+	for k, v := range metadata {
+		if strings.HasPrefix(k, cseMetaPrefix) {
+			cse[k] = v
+		}
+	}
+	attrs.Metadata = mergeMetadata(attrs.Metadata, cse)
+	return attrs
+}
+
+// content type by file extension, or by sniffing the beginning of the file for unknown extensions
+func detectContentType(name string, f *os.File) string {
+	if t := mime.TypeByExtension(filepath.Ext(name)); len(t) != 0 {
+		return t
+	}
+
+	buf := make([]byte, 512)
+	n, _ := f.ReadAt(buf, 0)
+	return http.DetectContentType(buf[:n])
+}
+
+// metadata with extra keys added, extra keys take precedence
+func mergeMetadata(metadata, extra map[string]string) map[string]string {
+	if len(extra) == 0 {
+		return metadata
+	}
+
+	merged := make(map[string]string, len(metadata)+len(extra))
+	for k, v := range metadata {
+		merged[k] = v
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return merged
+}
+
+func (a ObjectAttributes) applyToPut(input *s3.PutObjectInput) {
+	input.ContentType = stringOrNil(a.ContentType)
+	input.ContentEncoding = stringOrNil(a.ContentEncoding)
+	input.CacheControl = stringOrNil(a.CacheControl)
+	input.ContentDisposition = stringOrNil(a.ContentDisposition)
+	input.Expires = a.Expires
+	input.Metadata = a.Metadata
+	input.Tagging = stringOrNil(a.tagging)
+}
+
+func (a ObjectAttributes) applyToCreateMultipart(input *s3.CreateMultipartUploadInput) {
+	input.ContentType = stringOrNil(a.ContentType)
+	input.ContentEncoding = stringOrNil(a.ContentEncoding)
+	input.CacheControl = stringOrNil(a.CacheControl)
+	input.ContentDisposition = stringOrNil(a.ContentDisposition)
+	input.Expires = a.Expires
+	input.Metadata = a.Metadata
+	input.Tagging = stringOrNil(a.tagging)
+}
+
+func (a ObjectAttributes) applyToCopy(input *s3.CopyObjectInput) {
+	input.ContentType = stringOrNil(a.ContentType)
+	input.ContentEncoding = stringOrNil(a.ContentEncoding)
+	input.CacheControl = stringOrNil(a.CacheControl)
+	input.ContentDisposition = stringOrNil(a.ContentDisposition)
+	input.Expires = a.Expires
+	input.Metadata = a.Metadata
+}
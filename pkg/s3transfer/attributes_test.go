@@ -0,0 +1,121 @@
+package s3transfer
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyDetectsContentType(t *testing.T) {
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{
+		"index.html": "<p>hello</p>",
+		"data":       "<html><body>hello</body></html>",
+		"blob":       "\x00\x01\x02",
+		"style.css":  "p {}",
+	})
+
+	cases := []struct {
+		name string
+		opts Options
+		file string
+		want string
+	}{
+		{"extension", Options{}, "index.html", "text/html; charset=utf-8"},
+		{"sniffed", Options{}, "data", "text/html; charset=utf-8"},
+		{"binary", Options{}, "blob", "application/octet-stream"},
+		{"given", Options{Attributes: func(string) ObjectAttributes { return ObjectAttributes{ContentType: "text/x-custom"} }}, "style.css", "text/x-custom"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, fake, _ := newFakeClient(c.opts, "bucket")
+			_, err := client.Copy(context.Background(), filepath.Join(src, c.file), "s3://bucket/"+c.file)
+			require.NoError(t, err)
+
+			output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String(c.file)})
+			require.NoError(t, err)
+			if got := aws.ToString(output.ContentType); got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCopyWithAttributes(t *testing.T) {
+	attrs := func(string) ObjectAttributes {
+		return ObjectAttributes{CacheControl: "max-age=60", ContentEncoding: "gzip", Metadata: map[string]string{"team": "web"}}
+	}
+	client, fake, _ := newFakeClient(Options{Attributes: attrs}, "bucket")
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{"a.json": "{}"})
+
+	_, err := client.Copy(context.Background(), filepath.Join(src, "a.json"), "s3://bucket/a.json")
+	require.NoError(t, err)
+	_, err = client.Copy(context.Background(), "s3://bucket/a.json", "s3://bucket/b.json")
+	require.NoError(t, err)
+
+	for _, key := range []string{"a.json", "b.json"} {
+		output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String(key)})
+		require.NoError(t, err)
+		require.Equal(t, "application/json", aws.ToString(output.ContentType))
+		require.Equal(t, "max-age=60", aws.ToString(output.CacheControl))
+		require.Equal(t, "gzip", aws.ToString(output.ContentEncoding))
+		require.Equal(t, map[string]string{"team": "web"}, output.Metadata)
+	}
+}
+
+func TestAttributesByRelativePath(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	attrs := func(p string) ObjectAttributes {
+		mu.Lock()
+		paths = append(paths, p)
+		mu.Unlock()
+		if p == "static/x.css" {
+			return ObjectAttributes{CacheControl: "max-age=60"}
+		}
+		return ObjectAttributes{}
+	}
+	client, fake, _ := newFakeClient(Options{Attributes: attrs, MetadataDirective: types.MetadataDirectiveReplace}, "bucket")
+	src := filepath.Join(t.TempDir(), "site")
+	writeTestFiles(t, src, map[string]string{"static/x.css": "a {}", "index.html": "<p>a</p>"})
+
+	_, err := client.Copy(context.Background(), src, "s3://bucket/")
+	require.NoError(t, err)
+	_, err = client.Copy(context.Background(), "s3://bucket/site/", "s3://bucket/copy/")
+	require.NoError(t, err)
+
+	sort.Strings(paths)
+	require.Equal(t, []string{"index.html", "index.html", "static/x.css", "static/x.css"}, paths)
+	for _, key := range []string{"site/static/x.css", "copy/static/x.css"} {
+		output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String(key)})
+		require.NoError(t, err)
+		require.Equal(t, "max-age=60", aws.ToString(output.CacheControl), key)
+		require.Equal(t, "text/css; charset=utf-8", aws.ToString(output.ContentType), key)
+	}
+}
+
+func TestEncryptedUploadHasNoContentType(t *testing.T) {
+	ce, err := NewClientEncryption(bytes.Repeat([]byte{3}, 32))
+	require.NoError(t, err)
+	ce.EncryptUploads = true
+	client, fake, _ := newFakeClient(Options{ClientEncryption: ce}, "bucket")
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{"index.html": "<p>hello</p>"})
+
+	_, err = client.Copy(context.Background(), filepath.Join(src, "index.html"), "s3://bucket/index.html")
+	require.NoError(t, err)
+
+	output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("index.html")})
+	require.NoError(t, err)
+	require.Nil(t, output.ContentType)
+}
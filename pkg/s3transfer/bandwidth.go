@@ -0,0 +1,136 @@
+package s3transfer
+
+import (
+	"context"
+	"io"
+
+	"golang.org/x/time/rate"
+)
+
+// maximum number of bytes a reader may take from a bandwidth limiter at once
+const maxBandwidthBurst = 256 * 1024
+
+// create a limiter for bytes per second, nil if bps is not positive
+func newBandwidthLimiter(bps float64) *rate.Limiter {
+	if bps <= 0 {
+		return nil
+	}
+
+	burst := min(max(int(bps), 1), maxBandwidthBurst)
+	return rate.NewLimiter(rate.Limit(bps), burst)
+}
+
+// bandwidth limiters shared by all workers, nil entries mean unlimited
+type bandwidthLimiters struct {
+	total    *rate.Limiter
+	upload   *rate.Limiter
+	download *rate.Limiter
+}
+
+func newBandwidthLimiters(total, upload, download float64) bandwidthLimiters {
+	return bandwidthLimiters{
+		total:    newBandwidthLimiter(total),
+		upload:   newBandwidthLimiter(upload),
+		download: newBandwidthLimiter(download),
+	}
+}
+
+func (b bandwidthLimiters) wrapUpload(ctx context.Context, r io.Reader) io.Reader {
+	return limitReader(ctx, r, b.total, b.upload)
+}
+
+func (b bandwidthLimiters) wrapDownload(ctx context.Context, r io.Reader) io.Reader {
+	return limitReader(ctx, r, b.total, b.download)
+}
+
+// limitedReader throttles reads so that they do not exceed any of the limiters
+type limitedReader struct {
+	ctx      context.Context
+	r        io.Reader
+	limiters []*rate.Limiter
+	burst    int
+}
+
+func (lr *limitedReader) Read(b []byte) (int, error) {
+	if len(b) > lr.burst {
+		b = b[:lr.burst]
+	}
+
+	n, err := lr.r.Read(b)
+	if n <= 0 {
+		return n, err
+	}
+
+	if werr := lr.wait(n); werr != nil {
+		return n, werr
+	}
+	return n, err
+}
+
+// take n bytes from all limiters
+func (lr *limitedReader) wait(n int) error {
+	for _, l := range lr.limiters {
+		if err := l.WaitN(lr.ctx, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitedReadSeeker keeps the wrapped body seekable, the SDK rewinds seekable bodies after reading them for
+// computing checksums and signatures, and before retries. Bytes are charged to the limiters once, so that
+// reading a body again after rewinding it does not halve the rate.
+type limitedReadSeeker struct {
+	limitedReader
+	pos int64
+	// end of the bytes charged so far
+	charged int64
+}
+
+func (lrs *limitedReadSeeker) Read(b []byte) (int, error) {
+	if len(b) > lrs.burst {
+		b = b[:lrs.burst]
+	}
+
+	n, err := lrs.r.Read(b)
+	lrs.pos += int64(n)
+	if lrs.pos <= lrs.charged {
+		return n, err
+	}
+
+	uncharged := lrs.pos - lrs.charged
+	lrs.charged = lrs.pos
+	if werr := lrs.wait(int(uncharged)); werr != nil {
+		return n, werr
+	}
+	return n, err
+}
+
+func (lrs *limitedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := lrs.r.(io.Seeker).Seek(offset, whence)
+	if err == nil {
+		lrs.pos = pos
+	}
+	return pos, err
+}
+
+// wrap reader with the given limiters, returns the reader itself when there is nothing to limit
+func limitReader(ctx context.Context, r io.Reader, limiters ...*rate.Limiter) io.Reader {
+	lr := limitedReader{ctx: ctx, r: r, burst: maxBandwidthBurst}
+	for _, l := range limiters {
+		if l == nil {
+			continue
+		}
+		lr.limiters = append(lr.limiters, l)
+		lr.burst = min(lr.burst, l.Burst())
+	}
+
+	if len(lr.limiters) == 0 {
+		return r
+	}
+
+	if _, ok := r.(io.Seeker); ok {
+		return &limitedReadSeeker{limitedReader: lr}
+	}
+	return &lr
+}
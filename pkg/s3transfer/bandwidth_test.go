@@ -0,0 +1,65 @@
+package s3transfer
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/time/rate"
+)
+
+func TestLimitReaderUnlimited(t *testing.T) {
+	r := strings.NewReader("foo")
+	got := limitReader(context.Background(), r, nil, nil)
+	require.Same(t, r, got)
+}
+
+func TestLimitReaderKeepsSeeker(t *testing.T) {
+	limiter := rate.NewLimiter(rate.Inf, 4)
+	r := limitReader(context.Background(), strings.NewReader("0123456789"), limiter)
+	_, ok := r.(io.Seeker)
+	require.True(t, ok)
+
+	// reads are split into chunks no larger than limiter burst
+	buf := make([]byte, 10)
+	n, err := r.Read(buf)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "456789", string(b))
+}
+
+func TestLimitReaderCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	limiter := rate.NewLimiter(1, 1)
+	limiter.Allow()
+	r := limitReader(ctx, strings.NewReader("0123456789"), limiter)
+
+	_, err := io.ReadAll(r)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestLimitReaderChargesRereadsOnce(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	limiter := rate.NewLimiter(1, 10)
+	r := limitReader(ctx, strings.NewReader("0123456789"), limiter)
+	b, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(b))
+
+	// reading again after rewinding takes no tokens, it would fail waiting for them once cancelled
+	cancel()
+	_, err = r.(io.Seeker).Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	b, err = io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(b))
+}
@@ -0,0 +1,281 @@
+package s3transfer
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+var (
+	// ErrChecksumMismatch is returned for downloads whose contents do not match the composite checksum of their
+	// object, mismatches of full object checksums are returned by the SDK
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	// ErrNoChecksum is returned for downloads of objects stored without checksums when checksums are verified
+	ErrNoChecksum = errors.New("object has no checksum")
+)
+
+// ValidateChecksumAlgorithm returns an error if a is not an algorithm S3 computes checksums with
+func ValidateChecksumAlgorithm(a types.ChecksumAlgorithm) error {
+	if !slices.Contains(a.Values(), a) {
+		return fmt.Errorf("unknown checksum algorithm %q, should be one of %v", a, a.Values())
+	}
+	return nil
+}
+
+func newChecksumHash(a types.ChecksumAlgorithm) hash.Hash {
+	switch a {
+	case types.ChecksumAlgorithmCrc32:
+		return crc32.NewIEEE()
+	case types.ChecksumAlgorithmCrc32c:
+		return crc32.New(crc32.MakeTable(crc32.Castagnoli))
+	case types.ChecksumAlgorithmSha1:
+		return sha1.New()
+	default:
+		return sha256.New()
+	}
+}
+
+// base64 checksum of contents of r as S3 expects it
+func computeChecksum(a types.ChecksumAlgorithm, r io.Reader) (string, error) {
+	h := newChecksumHash(a)
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)), nil
+}
+
+// checksum field of algorithm a among fields of a request or response, given in order CRC32, CRC32C, SHA1, SHA256
+func checksumField(a types.ChecksumAlgorithm, crc32, crc32c, sha1, sha256 **string) **string {
+	switch a {
+	case types.ChecksumAlgorithmCrc32:
+		return crc32
+	case types.ChecksumAlgorithmCrc32c:
+		return crc32c
+	case types.ChecksumAlgorithmSha1:
+		return sha1
+	default:
+		return sha256
+	}
+}
+
+// the SDK computes the checksum while sending the body and sends it as a trailer, so that the body is read once
+func (c *Client) applyChecksumToPut(input *s3.PutObjectInput) {
+	input.ChecksumAlgorithm = c.opts.Checksum
+}
+
+func (c *Client) applyChecksumToUploadPart(input *s3.UploadPartInput) {
+	input.ChecksumAlgorithm = c.opts.Checksum
+}
+
+// completed parts carry the checksums S3 returned for them
+func (c *Client) applyChecksumToCompletedPart(part *types.CompletedPart, output *s3.UploadPartOutput) {
+	part.ChecksumCRC32, part.ChecksumCRC32C = output.ChecksumCRC32, output.ChecksumCRC32C
+	part.ChecksumSHA1, part.ChecksumSHA256 = output.ChecksumSHA1, output.ChecksumSHA256
+}
+
+// checksums are only returned when asked for. The SDK validates full object checksums of responses itself.
+func (c *Client) applyChecksumToGet(input *s3.GetObjectInput) {
+	if len(c.opts.Checksum) != 0 {
+		input.ChecksumMode = types.ChecksumModeEnabled
+	}
+}
+
+// checksum of an object among checksum fields of a response, the configured algorithm is preferred when the
+// object has several
+func (c *Client) objectChecksum(crc32, crc32c, sha1, sha256 *string) (types.ChecksumAlgorithm, string) {
+	algorithms := append([]types.ChecksumAlgorithm{c.opts.Checksum}, types.ChecksumAlgorithm("").Values()...)
+	for _, a := range algorithms {
+		if v := aws.ToString(*checksumField(a, &crc32, &crc32c, &sha1, &sha256)); len(v) != 0 {
+			return a, v
+		}
+	}
+	return "", ""
+}
+
+// checksum of file at path computed as stored checksum s, composite checksums are computed over parts of the
+// same sizes
+func fileChecksum(path string, s storedChecksum) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	if s.partSizes == nil {
+		return computeChecksum(s.algorithm, f)
+	}
+
+	sizes, err := s.partSizes()
+	if err != nil {
+		return "", err
+	}
+	var sums []byte
+	for _, size := range sizes {
+		h := newChecksumHash(s.algorithm)
+		if _, err := io.CopyN(h, f, size); err != nil {
+			return "", err
+		}
+		sums = h.Sum(sums)
+	}
+	h := newChecksumHash(s.algorithm)
+	h.Write(sums)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil)) + "-" + strconv.Itoa(len(sizes)), nil
+}
+
+// checksumVerifier hashes contents of an object read through it and compares them with the composite checksum
+// of the object at the end. Composite checksums of multipart uploads are checksums of checksums of their parts,
+// which the SDK cannot validate, so parts are hashed separately.
+type checksumVerifier struct {
+	r         io.Reader
+	algorithm types.ChecksumAlgorithm
+	want      string
+	hash      hash.Hash
+	// sizes of parts not read yet and bytes left in the current part
+	parts    []int64
+	partLeft int64
+	partSums []byte
+	// result of the comparison, set once r is exhausted
+	done bool
+	err  error
+}
+
+// verifier of body of a downloaded object with a composite checksum, nil when checksums are not verified
+// or the SDK validates the full object checksum
+func (c *Client) newChecksumVerifier(ctx context.Context, bucket, key string, output *s3.GetObjectOutput) (*checksumVerifier, error) {
+	if len(c.opts.Checksum) == 0 {
+		return nil, nil
+	}
+
+	algorithm, checksum := c.objectChecksum(output.ChecksumCRC32, output.ChecksumCRC32C, output.ChecksumSHA1, output.ChecksumSHA256)
+	if len(algorithm) == 0 {
+		return nil, ErrNoChecksum
+	}
+
+	sum, parts, ok := strings.Cut(checksum, "-")
+	if !ok {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(parts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid composite checksum %q", checksum)
+	}
+
+	v := &checksumVerifier{r: output.Body, algorithm: algorithm, want: sum, hash: newChecksumHash(algorithm)}
+	if v.parts, err = c.partSizes(ctx, bucket, key, n, isCustomerEncrypted(output.SSECustomerAlgorithm)); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// sizes of parts of object uploaded in n parts, SSE-C objects are read with the customer key
+func (c *Client) partSizes(ctx context.Context, bucket, key string, n int, ssec bool) ([]int64, error) {
+	sizes := make([]int64, 0, n)
+	var marker *string
+	for {
+		input := &s3.GetObjectAttributesInput{
+			Bucket:           aws.String(bucket),
+			Key:              aws.String(key),
+			ObjectAttributes: []types.ObjectAttributes{types.ObjectAttributesObjectParts},
+			PartNumberMarker: marker,
+		}
+		c.opts.Encryption.applyToGetAttributes(input, ssec)
+
+		reqCtx, cancel := c.requestContext(ctx)
+		output, err := c.api.GetObjectAttributes(reqCtx, input)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if output.ObjectParts == nil {
+			break
+		}
+
+		for _, p := range output.ObjectParts.Parts {
+			sizes = append(sizes, aws.ToInt64(p.Size))
+		}
+		if !aws.ToBool(output.ObjectParts.IsTruncated) {
+			break
+		}
+		marker = output.ObjectParts.NextPartNumberMarker
+	}
+
+	if len(sizes) != n {
+		return nil, fmt.Errorf("object has composite checksum of %d parts, found %d parts", n, len(sizes))
+	}
+	return sizes, nil
+}
+
+func (v *checksumVerifier) Read(p []byte) (int, error) {
+	if v.done {
+		return 0, v.err
+	}
+
+	if len(p) > 0 {
+		if v.partLeft == 0 && len(v.parts) > 0 {
+			v.partLeft, v.parts = v.parts[0], v.parts[1:]
+		}
+		if v.partLeft > 0 && int64(len(p)) > v.partLeft {
+			p = p[:v.partLeft]
+		}
+	}
+
+	n, err := v.r.Read(p)
+	v.hash.Write(p[:n])
+	if n > 0 {
+		v.partLeft -= int64(n)
+		if v.partLeft == 0 {
+			v.partSums = v.hash.Sum(v.partSums)
+			v.hash.Reset()
+		}
+	}
+
+	if errors.Is(err, io.EOF) {
+		v.done, v.err = true, v.verify()
+		return n, v.err
+	}
+	return n, err
+}
+
+// compare checksum of contents read with the expected one, returns io.EOF when they match
+func (v *checksumVerifier) verify() error {
+	h := newChecksumHash(v.algorithm)
+	h.Write(v.partSums)
+	sum := h.Sum(nil)
+
+	if got := base64.StdEncoding.EncodeToString(sum); got != v.want {
+		return fmt.Errorf("%w: %s of contents is %s, object has %s", ErrChecksumMismatch, v.algorithm, got, v.want)
+	}
+	return io.EOF
+}
+
+// verifiedReader is contents of a downloaded object, whose checksum is compared by the SDK or the verifier
+// once src is read to its end. Readers of contents, i.e. decryption, may stop before the end of src, so the
+// rest of src is read when they are done.
+type verifiedReader struct {
+	r   io.Reader
+	src io.Reader
+}
+
+func (r *verifiedReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if errors.Is(err, io.EOF) {
+		if _, verr := io.Copy(io.Discard, r.src); verr != nil {
+			return n, verr
+		}
+	}
+	return n, err
+}
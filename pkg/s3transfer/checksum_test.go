@@ -0,0 +1,200 @@
+package s3transfer
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/internal/s3fake"
+	"github.com/stretchr/testify/require"
+)
+
+// corruptingAPI flips the first byte of downloaded objects
+type corruptingAPI struct {
+	*s3fake.Client
+}
+
+func (a corruptingAPI) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	output, err := a.Client.GetObject(ctx, params, optFns...)
+	if err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return nil, err
+	}
+	data[0] ^= 0xff
+	output.Body = io.NopCloser(bytes.NewReader(data))
+	return output, nil
+}
+
+func TestComputeChecksum(t *testing.T) {
+	cases := []struct {
+		algorithm types.ChecksumAlgorithm
+		want      string
+	}{
+		{types.ChecksumAlgorithmCrc32, "DUoRhQ=="},
+		{types.ChecksumAlgorithmCrc32c, "yZRlqg=="},
+		{types.ChecksumAlgorithmSha1, "Kq5sNclPz7QV2+lfQIuc6R7oRu0="},
+		{types.ChecksumAlgorithmSha256, "uU0nuZNNPgilLlLX2n2r+sSE7+N6U4DukIj3rOLvzek="},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.algorithm), func(t *testing.T) {
+			got, err := computeChecksum(c.algorithm, strings.NewReader("hello world"))
+			require.NoError(t, err)
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCopyChecksum(t *testing.T) {
+	for _, algorithm := range types.ChecksumAlgorithm("").Values() {
+		t.Run(string(algorithm), func(t *testing.T) {
+			client, fake, _ := newFakeClient(Options{Checksum: algorithm}, "bucket")
+			src := filepath.Join(t.TempDir(), "a")
+			require.NoError(t, os.WriteFile(src, []byte("hello world"), 0644))
+
+			_, err := client.Copy(context.Background(), src, "s3://bucket/a")
+			require.NoError(t, err)
+
+			output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("a"), ChecksumMode: types.ChecksumModeEnabled})
+			require.NoError(t, err)
+			want, _ := computeChecksum(algorithm, strings.NewReader("hello world"))
+			got := aws.ToString(*checksumField(algorithm, &output.ChecksumCRC32, &output.ChecksumCRC32C, &output.ChecksumSHA1, &output.ChecksumSHA256))
+			require.Equal(t, want, got)
+
+			dest := filepath.Join(t.TempDir(), "a")
+			_, err = client.Copy(context.Background(), "s3://bucket/a", dest)
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestCopyChecksumMultipart(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{Checksum: types.ChecksumAlgorithmCrc32c}, "bucket")
+	err := client.uploadStream(context.Background(), "bucket", "key", strings.NewReader("0123456789"), 4, ObjectAttributes{}, writeCondition{})
+	require.NoError(t, err)
+
+	output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("key"), ChecksumMode: types.ChecksumModeEnabled})
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(aws.ToString(output.ChecksumCRC32C), "-3"), aws.ToString(output.ChecksumCRC32C))
+
+	dest := filepath.Join(t.TempDir(), "key")
+	_, err = client.Copy(context.Background(), "s3://bucket/key", dest)
+	require.NoError(t, err)
+	data, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(data))
+}
+
+func TestCopyChecksumMismatch(t *testing.T) {
+	fake := s3fake.New()
+	fake.CreateBucket("bucket", false)
+	uploader := New(fake, Options{Checksum: types.ChecksumAlgorithmSha256})
+	err := uploader.uploadStream(context.Background(), "bucket", "key", strings.NewReader("0123456789"), 4, ObjectAttributes{}, writeCondition{})
+	require.NoError(t, err)
+
+	client := New(corruptingAPI{fake}, Options{Checksum: types.ChecksumAlgorithmSha256})
+	dir := t.TempDir()
+	result, err := client.Copy(context.Background(), "s3://bucket/key", filepath.Join(dir, "key"))
+	require.ErrorIs(t, err, ErrChecksumMismatch)
+	require.Equal(t, int64(1), result.Failed)
+
+	// nothing is left behind, neither the file nor its temporary file
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+// full object checksums are validated by the SDK, which the fake bypasses
+func TestCopyChecksumMismatchValidatedBySDK(t *testing.T) {
+	checksum, _ := computeChecksum(types.ChecksumAlgorithmCrc32, strings.NewReader("hello world"))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-amz-checksum-crc32", checksum)
+		w.Header().Set("Content-Length", "11")
+		io.WriteString(w, "hellp world")
+	}))
+	defer srv.Close()
+
+	api := s3.New(s3.Options{
+		BaseEndpoint: aws.String(srv.URL),
+		Region:       "us-east-1",
+		UsePathStyle: true,
+		Credentials:  aws.AnonymousCredentials{},
+	})
+	client := New(api, Options{Checksum: types.ChecksumAlgorithmCrc32})
+	dir := t.TempDir()
+	result, err := client.Copy(context.Background(), "s3://bucket/key", filepath.Join(dir, "key"))
+	require.Error(t, err)
+	require.Equal(t, int64(1), result.Failed)
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestCopyChecksumClientEncrypted(t *testing.T) {
+	ce, err := NewClientEncryption(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	ce.EncryptUploads = true
+	client, _, _ := newFakeClient(Options{Checksum: types.ChecksumAlgorithmCrc32, ClientEncryption: ce}, "bucket")
+	src := filepath.Join(t.TempDir(), "a")
+	require.NoError(t, os.WriteFile(src, []byte("hello world"), 0644))
+
+	_, err = client.Copy(context.Background(), src, "s3://bucket/a")
+	require.NoError(t, err)
+
+	// checksum is of encrypted contents, verified even though decryption does not read the body to its end
+	dest := filepath.Join(t.TempDir(), "a")
+	_, err = client.Copy(context.Background(), "s3://bucket/a", dest)
+	require.NoError(t, err)
+	data, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, "hello world", string(data))
+}
+
+func TestCopyChecksumMissing(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{Checksum: types.ChecksumAlgorithmCrc32}, "bucket")
+	fake.Put("bucket", "a", []byte("a"))
+
+	_, err := client.Copy(context.Background(), "s3://bucket/a", filepath.Join(t.TempDir(), "a"))
+	require.ErrorIs(t, err, ErrNoChecksum)
+}
+
+func TestChecksumVerifierParts(t *testing.T) {
+	parts := []string{"0123", "4567", "89"}
+	var sums []byte
+	for _, p := range parts {
+		h := newChecksumHash(types.ChecksumAlgorithmSha1)
+		h.Write([]byte(p))
+		sums = h.Sum(sums)
+	}
+	want, _ := computeChecksum(types.ChecksumAlgorithmSha1, bytes.NewReader(sums))
+
+	v := &checksumVerifier{
+		// reads cross part boundaries unless the verifier splits them
+		r:         iotest.HalfReader(strings.NewReader(strings.Join(parts, ""))),
+		algorithm: types.ChecksumAlgorithmSha1,
+		want:      want,
+		hash:      newChecksumHash(types.ChecksumAlgorithmSha1),
+		parts:     []int64{4, 4, 2},
+	}
+	data, err := io.ReadAll(v)
+	require.NoError(t, err)
+	require.Equal(t, "0123456789", string(data))
+	require.Equal(t, base64.StdEncoding.EncodeToString(sums), base64.StdEncoding.EncodeToString(v.partSums))
+}
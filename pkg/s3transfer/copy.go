@@ -0,0 +1,721 @@
+package s3transfer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+type copier interface {
+	copyFromS3ToS3(ctx context.Context, src, dest string, result *Result) error
+	copyFromS3ToLocal(ctx context.Context, src, dest string, result *Result) error
+	copyFromLocalToS3(ctx context.Context, src, dest string, result *Result) error
+}
+
+// Copy copies src to dest, either of which can be a local path or an s3 path. S3 paths ending with / or *
+// copy all objects under the prefix and local directories are copied recursively.
+func (c *Client) Copy(ctx context.Context, src, dest string) (Result, error) {
+	var result Result
+	err := executeCopy(ctx, c, src, dest, &result)
+	return result, err
+}
+
+func executeCopy(ctx context.Context, client copier, src, dest string, result *Result) error {
+	switch {
+	case IsS3Path(src) && IsS3Path(dest):
+		return client.copyFromS3ToS3(ctx, src, dest, result)
+	case IsS3Path(src):
+		return client.copyFromS3ToLocal(ctx, src, dest, result)
+	case IsS3Path(dest):
+		return client.copyFromLocalToS3(ctx, src, dest, result)
+	default:
+		return errors.New("local to local copy is not supported")
+	}
+}
+
+func (c *Client) copyFromLocalToS3(ctx context.Context, src, dest string, result *Result) error {
+	info, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+
+	if !info.IsDir() {
+		ctx, cancel := inFlightContext(ctx)
+		defer cancel()
+
+		c.progress.AddTotal(info.Size())
+		start := time.Now()
+		path, n, err := c.copySingleToS3(ctx, src, filepath.Base(src), dest)
+		c.report(result, newEvent(OpUpload, src, path, n, start, err))
+		return failure(err)
+	}
+
+	fnch := make(chan func() error, c.opts.Concurrency)
+	evch := make(chan Event, c.opts.Concurrency)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	run := c.runPooled(ctx, cancel, fnch, evch, result)
+	work, cancelWork := inFlightContext(ctx)
+	defer cancelWork()
+
+	if strings.HasSuffix(dest, "/") {
+		dest += info.Name()
+	} else {
+		dest = dest + "/" + info.Name()
+	}
+
+	dcp := &directoryCopier{
+		dest:          dest,
+		srcRoot:       src,
+		fnch:          fnch,
+		evch:          evch,
+		copyFunc:      c.copySingleToS3,
+		ctx:           ctx,
+		workCtx:       work,
+		progress:      c.progress,
+		pathSeparator: filepath.Separator,
+	}
+
+	err = filepath.WalkDir(src, dcp.walkDirFunc)
+
+	close(fnch)
+	if werr := run.wait(result); werr != nil {
+		return werr
+	}
+
+	return err
+}
+
+type directoryCopier struct {
+	dest    string
+	srcRoot string
+	fnch    chan<- func() error
+	evch    chan<- Event
+	// ctx stops walking, uploads run with workCtx
+	ctx     context.Context
+	workCtx context.Context
+	// uploads file to s3 path, given the path of the file relative to srcRoot
+	copyFunc      func(ctx context.Context, src, rel, dest string) (string, int64, error)
+	pathSeparator rune
+	progress      Progress
+}
+
+func (dcp *directoryCopier) walkDirFunc(path string, d fs.DirEntry, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if d.IsDir() {
+		return nil
+	}
+
+	rel := dcp.relativePath(path)
+	remotepath := dcp.dest + "/" + rel
+
+	if dcp.progress != nil {
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		dcp.progress.AddTotal(info.Size())
+	}
+
+	select {
+	case <-dcp.ctx.Done():
+		return filepath.SkipAll
+	case dcp.fnch <- func() error {
+		start := time.Now()
+		s3path, n, err := dcp.copyFunc(dcp.workCtx, path, rel, remotepath)
+		dcp.evch <- newEvent(OpUpload, path, s3path, n, start, err)
+		return failure(err)
+	}:
+		return nil
+	}
+
+}
+
+// generate remote path from local path for upload
+func (dcp *directoryCopier) generateRemotePath(path string) string {
+	return dcp.dest + "/" + dcp.relativePath(path)
+}
+
+// slash separated path of a file under srcRoot relative to it
+func (dcp *directoryCopier) relativePath(path string) string {
+	subpath := strings.TrimPrefix(path, dcp.srcRoot)
+	if dcp.pathSeparator != '/' {
+		subpath = strings.ReplaceAll(subpath, string(dcp.pathSeparator), "/")
+	}
+	return strings.TrimPrefix(subpath, "/")
+}
+
+// upload file src to s3 path dest, returns s3 path of the object and number of bytes uploaded.
+// rel is the path of src relative to the copied directory, attributes are chosen by it.
+func (c *Client) copySingleToS3(ctx context.Context, src, rel, dest string) (string, int64, error) {
+	bucket, key, err := ParsePath(dest)
+	if err != nil {
+		return dest, 0, err
+	}
+
+	f, err := os.Open(src)
+	if err != nil {
+		return dest, 0, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return dest, 0, err
+	}
+
+	if strings.HasSuffix(key, "/") {
+		key += f.Name()
+	}
+	s3path := S3Path(bucket, key)
+
+	cond, err := c.prepareWrite(ctx, bucket, key, true, func() (version, error) { return fileVersion(src, info), nil })
+	if err != nil {
+		// files skipped by overwrite checks are done as well
+		c.progress.StartFile(src, info.Size()).Finish()
+		return s3path, 0, err
+	}
+
+	var body io.ReadSeeker = f
+	size := info.Size()
+	ce := c.opts.ClientEncryption
+	encrypt := ce != nil && ce.EncryptUploads
+	attrs := c.uploadAttributes(src, rel, f, encrypt)
+	if c.opts.Preserve {
+		attrs.Metadata = mergeMetadata(attrs.Metadata, fileAttributesMetadata(info))
+	}
+	if encrypt {
+		obj, metadata, err := ce.newObject(info.Size())
+		if err != nil {
+			return s3path, 0, err
+		}
+		body, size = newEncryptingReader(obj, f), obj.encryptedSize()
+		attrs.Metadata = mergeMetadata(attrs.Metadata, metadata)
+	}
+
+	fp := c.progress.StartFile(src, size)
+	defer fp.Finish()
+
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Body:   c.bandwidth.wrapUpload(ctx, wrapReader(body, fp)),
+	}
+	attrs.applyToPut(input)
+	c.opts.Storage.applyToPut(input)
+	c.opts.Encryption.applyToPut(input)
+	c.applyChecksumToPut(input)
+	_, err = c.api.PutObject(ctx, input, cond.options()...)
+
+	if err != nil {
+		return s3path, 0, cond.check(err)
+	}
+
+	return s3path, info.Size(), nil
+}
+
+func (c *Client) copyFromS3ToLocal(ctx context.Context, src, dest string, result *Result) error {
+	if !strings.HasSuffix(src, "/") && !strings.HasSuffix(src, "*") {
+		ctx, cancel := inFlightContext(ctx)
+		defer cancel()
+
+		start := time.Now()
+		path, n, err := c.copySingleFromS3ToLocal(ctx, src, dest)
+		c.report(result, newEvent(OpDownload, src, path, n, start, err))
+		return failure(err)
+	}
+
+	src = strings.TrimSuffix(src, "*")
+
+	bucket, prefix, err := ParsePath(src)
+	if err != nil {
+		return err
+	}
+
+	fnch := make(chan func() error, c.opts.Concurrency)
+	evch := make(chan Event, c.opts.Concurrency)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	run := c.runPooled(ctx, cancel, fnch, evch, result)
+	work, cancelWork := inFlightContext(ctx)
+	defer cancelWork()
+
+	waiter := newRestoreWaiter(c, fnch, work)
+	lsParams := ListParams{Bucket: bucket, Prefix: prefix}
+	err = c.List(ctx, lsParams, func(output *s3.ListObjectsV2Output) {
+	loop:
+		for _, o := range output.Contents {
+			select {
+			case <-ctx.Done():
+				break loop
+			default:
+				o := o
+				c.progress.AddTotal(aws.ToInt64(o.Size))
+				waiter.add(ctx, bucket, o, c.downloadFunc(work, bucket, aws.ToString(o.Key), aws.ToInt64(o.Size), prefix, dest, evch))
+			}
+		}
+	})
+	if err == nil {
+		waiter.wait(ctx)
+	}
+
+	close(fnch)
+	if werr := run.wait(result); werr != nil {
+		return werr
+	}
+	result.Skipped += waiter.waiting()
+
+	return err
+}
+
+// pooled download of key of size under prefix running with ctx
+func (c *Client) downloadFunc(ctx context.Context, bucket, key string, size int64, prefix, dest string, evch chan<- Event) func() error {
+	return func() error {
+		start := time.Now()
+		path, n, err := c.downloadUnderPrefix(ctx, bucket, key, size, prefix, dest)
+		evch <- newEvent(OpDownload, S3Path(bucket, key), path, n, start, err)
+		return failure(err)
+	}
+}
+
+// download key of size found under prefix into directory dest, keeping the directory structure unless flattening
+func (c *Client) downloadUnderPrefix(ctx context.Context, bucket, key string, size int64, prefix, dest string) (string, int64, error) {
+	if c.opts.Flatten {
+		return c.downloadFile(ctx, bucket, key, size, dest)
+	}
+
+	path := convertToLocalPath(prefix, key, dest)
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return path, 0, err
+	}
+
+	return c.downloadFile(ctx, bucket, key, size, path)
+}
+
+// convert aws key excluding prefix to local path under dest as file stored subfolders
+func convertToLocalPath(prefix, key, dest string) string {
+	p := strings.TrimPrefix(key, prefix)
+	cols := []string{dest}
+	cols = append(cols, strings.Split(p, "/")...)
+	return filepath.Join(cols...)
+}
+
+func (c *Client) copySingleFromS3ToLocal(ctx context.Context, src, dest string) (string, int64, error) {
+	bucket, key, err := ParsePath(src)
+	if err != nil {
+		return dest, 0, err
+	}
+
+	output, err := c.Head(ctx, bucket, key)
+	if err != nil {
+		return dest, 0, err
+	}
+
+	size := aws.ToInt64(output.ContentLength)
+	c.progress.AddTotal(size)
+	return c.downloadFile(ctx, bucket, key, size, dest)
+}
+
+// download object of size into dest, returns path of the downloaded file and its size. The object is
+// reported to progress once, whether it is downloaded again after being restored or skipped.
+func (c *Client) downloadFile(ctx context.Context, bucket, key string, size int64, dest string) (string, int64, error) {
+	fp := c.progress.StartFile(key, size)
+	defer fp.Finish()
+
+	path, n, err := c.downloadObject(ctx, bucket, key, dest, fp)
+	if restored, err := c.handleArchived(ctx, bucket, key, err); !restored {
+		return path, n, err
+	}
+	return c.downloadObject(ctx, bucket, key, dest, fp)
+}
+
+func (c *Client) downloadObject(ctx context.Context, bucket string, key string, dest string, fp FileProgress) (string, int64, error) {
+	isdir, err := isDirectory(dest)
+	if err != nil {
+		return dest, 0, err
+	}
+
+	if isdir {
+		dest = filepath.Join(dest, extractS3FileName(key))
+	}
+
+	// existing files are checked before the object is requested, so that kept files cost no download
+	backup, err := c.prepareDownload(ctx, bucket, key, dest)
+	if err != nil {
+		return dest, 0, err
+	}
+
+	// request timeout covers reading the body as well
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	c.opts.Encryption.applyToGet(input)
+	c.applyChecksumToGet(input)
+	output, err := c.api.GetObject(ctx, input)
+
+	if err != nil {
+		return dest, 0, err
+	}
+
+	defer output.Body.Close()
+
+	verifier, err := c.newChecksumVerifier(ctx, bucket, key, output)
+	if err != nil {
+		return dest, 0, err
+	}
+
+	size := aws.ToInt64(output.ContentLength)
+	body := c.bandwidth.wrapDownload(ctx, wrapReader(output.Body, fp))
+	if verifier != nil {
+		verifier.r = body
+		body = verifier
+	}
+	if isClientEncrypted(output.Metadata) {
+		obj, err := c.opts.ClientEncryption.openObject(output.Metadata)
+		if err != nil {
+			return dest, 0, err
+		}
+		src := body
+		body, size = newDecryptingReader(obj, src, 0), obj.size
+		if len(c.opts.Checksum) != 0 {
+			body = &verifiedReader{r: body, src: src}
+		}
+	}
+
+	err = writeFileAtomically(dest, body, backup)
+	if err != nil {
+		return dest, 0, err
+	}
+
+	if c.opts.Preserve {
+		if err := restoreFileAttributes(dest, output.Metadata, output.LastModified); err != nil {
+			return dest, 0, err
+		}
+	}
+	return dest, size, nil
+}
+
+// renames files, replaced by tests to fail renames
+var renameFile = os.Rename
+
+// suffix of temporary files downloads are written to before being renamed to their destination
+const downloadTempSuffix = ".s3cli-tmp"
+
+// write contents of r to a temporary file next to dest and rename it to dest once complete,
+// so that failed or interrupted downloads do not leave partial files behind.
+// Existing dest is moved to backup first unless backup is empty.
+func writeFileAtomically(dest string, r io.Reader, backup string) error {
+	tmp := dest + downloadTempSuffix
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(f, r)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+
+	backedUp := false
+	if err == nil && len(backup) != 0 {
+		err = renameFile(dest, backup)
+		backedUp = err == nil
+		if errors.Is(err, fs.ErrNotExist) {
+			err = nil
+		}
+	}
+
+	if err == nil {
+		err = renameFile(tmp, dest)
+		if err != nil && backedUp {
+			// do not leave dest missing, the backup is the file it had
+			if rerr := renameFile(backup, dest); rerr != nil {
+				slog.Warn("cannot restore backup", "path", dest, "backup", backup, "error", rerr)
+			}
+		}
+	}
+
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+func (c *Client) copyFromS3ToS3(ctx context.Context, src, dest string, result *Result) error {
+	if !strings.HasSuffix(src, "/") && !strings.HasSuffix(src, "*") {
+		ctx, cancel := inFlightContext(ctx)
+		defer cancel()
+
+		start := time.Now()
+		path, n, err := c.copySingleFromS3ToS3(ctx, src, dest)
+		c.report(result, newEvent(OpCopy, src, path, n, start, err))
+		return failure(err)
+	}
+
+	src = strings.TrimSuffix(src, "*")
+
+	srcBucket, prefix, err := ParsePath(src)
+	if err != nil {
+		return err
+	}
+
+	destBucket, destPrefix, err := ParsePath(dest)
+	if err != nil {
+		return err
+	}
+
+	if len(destPrefix) != 0 && !strings.HasSuffix(destPrefix, "/") {
+		destPrefix += "/"
+	}
+
+	fnch := make(chan func() error, c.opts.Concurrency)
+	evch := make(chan Event, c.opts.Concurrency)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	run := c.runPooled(ctx, cancel, fnch, evch, result)
+	work, cancelWork := inFlightContext(ctx)
+	defer cancelWork()
+
+	waiter := newRestoreWaiter(c, fnch, work)
+	lsParams := ListParams{Bucket: srcBucket, Prefix: prefix}
+	err = c.List(ctx, lsParams, func(output *s3.ListObjectsV2Output) {
+		for _, o := range output.Contents {
+			key, size := aws.ToString(o.Key), aws.ToInt64(o.Size)
+			destKey := destPrefix + strings.TrimPrefix(key, prefix)
+			if c.opts.Flatten {
+				destKey = destPrefix + extractS3FileName(key)
+			}
+
+			c.progress.AddTotal(size)
+			enqueued := waiter.add(ctx, srcBucket, o, func() error {
+				start := time.Now()
+				err := c.copyObject(work, srcBucket, key, strings.TrimPrefix(key, prefix), size, destBucket, destKey)
+				evch <- newEvent(OpCopy, S3Path(srcBucket, key), S3Path(destBucket, destKey), size, start, err)
+				return failure(err)
+			})
+			if !enqueued {
+				return
+			}
+		}
+	})
+	if err == nil {
+		waiter.wait(ctx)
+	}
+
+	close(fnch)
+	if werr := run.wait(result); werr != nil {
+		return werr
+	}
+	result.Skipped += waiter.waiting()
+
+	return err
+}
+
+// copy a single object, returns s3 path of the copy and its size
+func (c *Client) copySingleFromS3ToS3(ctx context.Context, src, dest string) (string, int64, error) {
+	srcBucket, srcKey, err := ParsePath(src)
+	if err != nil {
+		return dest, 0, err
+	}
+
+	destBucket, destKey, err := ParsePath(dest)
+	if err != nil {
+		return dest, 0, err
+	}
+
+	if len(destKey) == 0 || strings.HasSuffix(destKey, "/") {
+		destKey += extractS3FileName(srcKey)
+	}
+	destPath := S3Path(destBucket, destKey)
+
+	output, err := c.Head(ctx, srcBucket, srcKey)
+	if err != nil {
+		return destPath, 0, err
+	}
+
+	size := aws.ToInt64(output.ContentLength)
+	c.progress.AddTotal(size)
+	err = c.copyObject(ctx, srcBucket, srcKey, extractS3FileName(srcKey), size, destBucket, destKey)
+	if err != nil {
+		return destPath, 0, err
+	}
+
+	return destPath, size, nil
+}
+
+// maximum object size that can be copied with a single CopyObject request
+var maxCopyObjectSize int64 = 5 * 1024 * 1024 * 1024
+
+// copy object server side on the same endpoint, with CopyObject or multipart copy for large objects.
+// Objects on other endpoints are streamed through memory to the destination.
+// rel is the source key relative to the copied prefix, attributes of REPLACE copies are chosen by it.
+// The object is reported to progress once, whether it is copied again after being restored or skipped.
+func (c *Client) copyObject(ctx context.Context, srcBucket, srcKey, rel string, size int64, destBucket, destKey string) error {
+	fp := c.progress.StartFile(S3Path(srcBucket, srcKey), size)
+	defer fp.Finish()
+
+	cond, err := c.destination().prepareWrite(ctx, destBucket, destKey, !c.usesCopyObject(size), func() (version, error) {
+		output, err := c.headForCheck(ctx, srcBucket, srcKey)
+		if err != nil {
+			return version{}, err
+		}
+		return c.headVersion(ctx, srcBucket, srcKey, output), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	err = c.copyObjectOnce(ctx, srcBucket, srcKey, rel, size, destBucket, destKey, cond, fp)
+	if restored, err := c.handleArchived(ctx, srcBucket, srcKey, err); !restored {
+		return err
+	}
+	return c.copyObjectOnce(ctx, srcBucket, srcKey, rel, size, destBucket, destKey, cond, fp)
+}
+
+// copy object once reporting progress to fp, destination is written with cond unless copied with CopyObject.
+// CopyObject takes no conditions on the destination, so such copies rely on the checks made before.
+func (c *Client) copyObjectOnce(ctx context.Context, srcBucket, srcKey, rel string, size int64, destBucket, destKey string, cond writeCondition, fp FileProgress) error {
+	if c.usesCopyObject(size) {
+		ctx, cancel := c.requestContext(ctx)
+		defer cancel()
+
+		input := &s3.CopyObjectInput{
+			Bucket:     aws.String(destBucket),
+			Key:        aws.String(destKey),
+			CopySource: aws.String(copySource(srcBucket, srcKey)),
+		}
+		// source is headed for whether it is read with the customer key and for metadata of replaced copies
+		var head *s3.HeadObjectOutput
+		if c.opts.Encryption.CustomerKey != nil || c.opts.MetadataDirective == types.MetadataDirectiveReplace {
+			var err error
+			if head, err = c.Head(ctx, srcBucket, srcKey); err != nil {
+				return err
+			}
+		}
+		c.opts.Storage.applyToCopy(input)
+		c.opts.Encryption.applyToCopy(input, head != nil && isCustomerEncrypted(head.SSECustomerAlgorithm))
+		// S3 computes checksums of copies
+		input.ChecksumAlgorithm = c.opts.Checksum
+		if c.opts.MetadataDirective == types.MetadataDirectiveReplace {
+			input.MetadataDirective = types.MetadataDirectiveReplace
+			// metadata of client side encrypted objects is needed for reading them
+			c.copyAttributes(rel, head.Metadata).applyToCopy(input)
+		}
+		_, err := c.api.CopyObject(ctx, input)
+		if err != nil {
+			return err
+		}
+		fp.AddBytes(size)
+		return nil
+	}
+
+	if c.dest == nil {
+		return c.copyObjectMultipart(ctx, srcBucket, srcKey, rel, size, destBucket, destKey, cond, fp)
+	}
+
+	// body is read while parts are being uploaded, so request timeout is not applied to streamed copies
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(srcBucket),
+		Key:    aws.String(srcKey),
+	}
+	c.opts.Encryption.applyToGet(input)
+	c.applyChecksumToGet(input)
+	output, err := c.api.GetObject(ctx, input)
+	if err != nil {
+		return err
+	}
+	defer output.Body.Close()
+
+	verifier, err := c.newChecksumVerifier(ctx, srcBucket, srcKey, output)
+	if err != nil {
+		return err
+	}
+
+	body := c.bandwidth.wrapDownload(ctx, wrapReader(output.Body, fp))
+	if verifier != nil {
+		// contents are verified as they are read, a mismatch fails the upload before it completes
+		verifier.r = body
+		body = verifier
+	}
+	// attributes are kept as CopyObject does, metadata of client side encrypted objects is needed for reading them
+	attrs := attributesOf(output)
+	if c.opts.MetadataDirective == types.MetadataDirectiveReplace {
+		attrs = c.copyAttributes(rel, output.Metadata)
+	}
+	// tags are kept as CopyObject does unless tagging is given
+	if len(c.opts.Storage.Tagging) == 0 && aws.ToInt32(output.TagCount) != 0 {
+		tags, err := c.getTags(ctx, srcBucket, srcKey)
+		if err != nil {
+			return err
+		}
+		attrs.tagging = encodeTagging(tags)
+	}
+	return c.destination().uploadStream(ctx, destBucket, destKey, body, partSizeFor(size), attrs, cond)
+}
+
+// whether objects of size are copied with a single CopyObject request
+func (c *Client) usesCopyObject(size int64) bool {
+	return c.dest == nil && size <= maxCopyObjectSize
+}
+
+// url encoded bucket/key pair used as CopySource
+func copySource(bucket, key string) string {
+	return bucket + "/" + strings.ReplaceAll(url.PathEscape(key), "%2F", "/")
+}
+
+var errDirectoryNotExists = errors.New("directory does not exist")
+
+func isDirectory(name string) (bool, error) {
+	info, err := os.Stat(name)
+	if err == nil {
+		return info.IsDir(), nil
+	}
+
+	if os.IsNotExist(err) && strings.HasSuffix(name, string(filepath.Separator)) {
+		return false, errDirectoryNotExists
+	}
+
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+
+	return false, err
+}
+
+func extractS3FileName(s3key string) string {
+	idx := strings.LastIndexByte(s3key, '/')
+	if idx == -1 {
+		return s3key
+	}
+
+	return s3key[idx+1:]
+}
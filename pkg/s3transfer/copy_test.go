@@ -1,11 +1,16 @@
-package cmd
+package s3transfer
 
 import (
 	"context"
 	"errors"
+	"io"
 	"io/fs"
+	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+
+	"github.com/stretchr/testify/require"
 )
 
 type copyOperation int
@@ -34,17 +39,17 @@ type recordingClient struct {
 	op copyOperation
 }
 
-func (r *recordingClient) copyFromS3ToS3(src, dest string) error {
+func (r *recordingClient) copyFromS3ToS3(ctx context.Context, src, dest string, result *Result) error {
 	r.op = s3toS3
 	return nil
 }
 
-func (r *recordingClient) copyFromS3ToLocal(src, dest string) error {
+func (r *recordingClient) copyFromS3ToLocal(ctx context.Context, src, dest string, result *Result) error {
 	r.op = s3ToLocal
 	return nil
 }
 
-func (r *recordingClient) copyFromLocalToS3(src, dest string) error {
+func (r *recordingClient) copyFromLocalToS3(ctx context.Context, src, dest string, result *Result) error {
 	r.op = localToS3
 	return nil
 }
@@ -64,7 +69,7 @@ func TestExecuteCopy(t *testing.T) {
 	for _, c := range cases {
 		t.Run(c.name, func(t *testing.T) {
 			client := recordingClient{}
-			err := executeCopy(&client, c.inputSrc, c.inputDest)
+			err := executeCopy(context.Background(), &client, c.inputSrc, c.inputDest, &Result{})
 			if err != nil {
 				t.Errorf("Error unwanted here %s", err)
 			}
@@ -78,7 +83,7 @@ func TestExecuteCopy(t *testing.T) {
 
 func TestExecuteCopyLocalToLocal(t *testing.T) {
 	var client *recordingClient
-	err := executeCopy(client, "/tmp/foo.txt", "/tmp/bar.txt")
+	err := executeCopy(context.Background(), client, "/tmp/foo.txt", "/tmp/bar.txt", &Result{})
 	if err == nil {
 		t.Errorf("Error expected here %s", err)
 	}
@@ -181,3 +186,106 @@ func TestConvertToLocalPath(t *testing.T) {
 		})
 	}
 }
+
+func TestCopySource(t *testing.T) {
+	cases := []struct {
+		name        string
+		inputBucket string
+		inputKey    string
+		want        string
+	}{
+		{"plain key", "bucket", "foo/bar.txt", "bucket/foo/bar.txt"},
+		{"key with spaces", "bucket", "foo/my file.txt", "bucket/foo/my%20file.txt"},
+		{"key with special characters", "bucket", "date=2024/a+b?.txt", "bucket/date=2024/a+b%3F.txt"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := copySource(c.inputBucket, c.inputKey)
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestPartSizeFor(t *testing.T) {
+	cases := []struct {
+		name  string
+		input int64
+		want  int64
+	}{
+		{"small object", 1024, minPartSize},
+		{"maximum parts with minimum part size", minPartSize * maxUploadParts, minPartSize},
+		{"large object", 5 * 1024 * 1024 * 1024 * 1024, 549755814},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := partSizeFor(c.input)
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+
+			if got*maxUploadParts < c.input {
+				t.Errorf("part size %v too small for %v", got, c.input)
+			}
+		})
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read(b []byte) (int, error) {
+	return 0, errors.New("connection reset")
+}
+
+func TestWriteFileAtomically(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "test.txt")
+
+	err := writeFileAtomically(dest, strings.NewReader("foo"), "")
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, "foo", string(b))
+
+	_, err = os.Stat(dest + downloadTempSuffix)
+	require.True(t, os.IsNotExist(err), "temporary file should be renamed")
+}
+
+func TestWriteFileAtomicallyForError(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "test.txt")
+
+	err := writeFileAtomically(dest, io.MultiReader(strings.NewReader("foo"), failingReader{}), "")
+	require.Error(t, err)
+
+	_, err = os.Stat(dest)
+	require.True(t, os.IsNotExist(err), "partial download should not be left behind")
+
+	_, err = os.Stat(dest + downloadTempSuffix)
+	require.True(t, os.IsNotExist(err), "temporary file should be removed")
+}
+
+func TestWriteFileAtomicallyRestoresBackup(t *testing.T) {
+	defer func() { renameFile = os.Rename }()
+	renameFile = func(from, to string) error {
+		if strings.HasSuffix(from, downloadTempSuffix) {
+			return errors.New("permission denied")
+		}
+		return os.Rename(from, to)
+	}
+
+	dest := filepath.Join(t.TempDir(), "test.txt")
+	require.NoError(t, os.WriteFile(dest, []byte("old"), 0o644))
+
+	err := writeFileAtomically(dest, strings.NewReader("new"), dest+".bak")
+	require.ErrorContains(t, err, "permission denied")
+
+	b, err := os.ReadFile(dest)
+	require.NoError(t, err)
+	require.Equal(t, "old", string(b))
+
+	_, err = os.Stat(dest + ".bak")
+	require.True(t, os.IsNotExist(err), "backup should be moved back")
+}
@@ -0,0 +1,371 @@
+package s3transfer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Client side encryption uses an envelope scheme. Each object is encrypted with a random AES-256 data key,
+// which is stored in object metadata wrapped with AES-GCM by the master key. Contents are split into
+// segments encrypted separately with AES-GCM, so that objects can be encrypted and decrypted while
+// streaming and ranges can be decrypted without reading the whole object. Nonce of a segment is the
+// object IV with its index xored into the last 4 bytes, and the additional data marks the final segment
+// so that truncated objects are detected. Wrapped keys are bound to the IV and size stored next to them.
+const (
+	cseVersion     = "1"
+	cseSegmentSize = 64 * 1024
+	cseKeyLen      = 32
+
+	// user metadata keys of encrypted objects
+	cseMetaPrefix  = "s3cli-cse-"
+	cseMetaVersion = cseMetaPrefix + "version"
+	cseMetaKey     = cseMetaPrefix + "key"
+	cseMetaIV      = cseMetaPrefix + "iv"
+	cseMetaSalt    = cseMetaPrefix + "salt"
+	cseMetaSize    = cseMetaPrefix + "size"
+)
+
+// additional data of wrapped data keys binds them to the IV and plaintext size of their object, so that
+// metadata cannot be swapped between objects or changed without failing decryption
+func cseKeyWrapAAD(iv []byte, size int64) []byte {
+	aad := append([]byte("s3cli-cse-key-v"+cseVersion), iv...)
+	return binary.BigEndian.AppendUint64(aad, uint64(size))
+}
+
+var ErrNoClientKey = errors.New("object is client side encrypted but no client encryption key is given")
+
+// ClientEncryption encrypts uploads before they leave the client and decrypts downloads of encrypted objects
+type ClientEncryption struct {
+	// encrypt uploaded objects, otherwise the keys are only used for decrypting downloads
+	EncryptUploads bool
+
+	masterKey  []byte
+	passphrase []byte
+	// salt of keys derived for uploads, random per client
+	salt []byte
+
+	mu sync.Mutex
+	// keys derived from passphrase by salt
+	derived map[string][]byte
+}
+
+// NewClientEncryption uses a 256 bit master key for wrapping data keys
+func NewClientEncryption(masterKey []byte) (*ClientEncryption, error) {
+	if len(masterKey) != cseKeyLen {
+		return nil, errors.New("client encryption key should be 256 bits")
+	}
+	return &ClientEncryption{masterKey: masterKey}, nil
+}
+
+// NewPassphraseEncryption derives master keys from passphrase with scrypt, salt is stored in object metadata
+func NewPassphraseEncryption(passphrase string) (*ClientEncryption, error) {
+	if len(passphrase) == 0 {
+		return nil, errors.New("passphrase is empty")
+	}
+
+	salt, err := randomBytes(16)
+	if err != nil {
+		return nil, err
+	}
+	return &ClientEncryption{passphrase: []byte(passphrase), salt: salt, derived: make(map[string][]byte)}, nil
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// master key for salt, derivation is slow so derived keys are cached
+func (ce *ClientEncryption) keyEncryptionKey(salt []byte) ([]byte, error) {
+	if ce.masterKey != nil {
+		return ce.masterKey, nil
+	}
+
+	ce.mu.Lock()
+	defer ce.mu.Unlock()
+	if key, ok := ce.derived[string(salt)]; ok {
+		return key, nil
+	}
+
+	key, err := scrypt.Key(ce.passphrase, salt, 1<<15, 8, 1, cseKeyLen)
+	if err != nil {
+		return nil, err
+	}
+	ce.derived[string(salt)] = key
+	return key, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// cseObject is the encryption state of a single object
+type cseObject struct {
+	aead cipher.AEAD
+	iv   []byte
+	// size of plaintext
+	size int64
+}
+
+// create data key for an object of given size, returns metadata to store with the object
+func (ce *ClientEncryption) newObject(size int64) (*cseObject, map[string]string, error) {
+	dataKey, err := randomBytes(cseKeyLen)
+	if err != nil {
+		return nil, nil, err
+	}
+	iv, err := randomBytes(12)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kek, err := ce.keyEncryptionKey(ce.salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapper, err := newGCM(kek)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce, err := randomBytes(wrapper.NonceSize())
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped := wrapper.Seal(nonce, nonce, dataKey, cseKeyWrapAAD(iv, size))
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata := map[string]string{
+		cseMetaVersion: cseVersion,
+		cseMetaKey:     base64.StdEncoding.EncodeToString(wrapped),
+		cseMetaIV:      base64.StdEncoding.EncodeToString(iv),
+		cseMetaSize:    strconv.FormatInt(size, 10),
+	}
+	if ce.salt != nil {
+		metadata[cseMetaSalt] = base64.StdEncoding.EncodeToString(ce.salt)
+	}
+	return &cseObject{aead: aead, iv: iv, size: size}, metadata, nil
+}
+
+// isClientEncrypted reports whether object metadata belongs to a client side encrypted object
+func isClientEncrypted(metadata map[string]string) bool {
+	_, ok := metadata[cseMetaVersion]
+	return ok
+}
+
+// unwrap data key of an object from its metadata, ce may be nil
+func (ce *ClientEncryption) openObject(metadata map[string]string) (*cseObject, error) {
+	if ce == nil {
+		return nil, ErrNoClientKey
+	}
+	if v := metadata[cseMetaVersion]; v != cseVersion {
+		return nil, fmt.Errorf("unsupported client encryption version %q", v)
+	}
+
+	wrapped, err := base64.StdEncoding.DecodeString(metadata[cseMetaKey])
+	if err != nil {
+		return nil, fmt.Errorf("invalid wrapped key %w", err)
+	}
+	iv, err := base64.StdEncoding.DecodeString(metadata[cseMetaIV])
+	if err != nil || len(iv) != 12 {
+		return nil, errors.New("invalid client encryption iv")
+	}
+	size, err := strconv.ParseInt(metadata[cseMetaSize], 10, 64)
+	if err != nil || size < 0 {
+		return nil, errors.New("invalid client encryption size")
+	}
+
+	var salt []byte
+	if s, ok := metadata[cseMetaSalt]; ok {
+		if ce.passphrase == nil {
+			return nil, errors.New("object is encrypted with a passphrase, not a key")
+		}
+		if salt, err = base64.StdEncoding.DecodeString(s); err != nil {
+			return nil, fmt.Errorf("invalid salt %w", err)
+		}
+	} else if ce.masterKey == nil {
+		return nil, errors.New("object is encrypted with a key, not a passphrase")
+	}
+
+	kek, err := ce.keyEncryptionKey(salt)
+	if err != nil {
+		return nil, err
+	}
+	wrapper, err := newGCM(kek)
+	if err != nil {
+		return nil, err
+	}
+	if len(wrapped) < wrapper.NonceSize() {
+		return nil, errors.New("invalid wrapped key")
+	}
+	dataKey, err := wrapper.Open(nil, wrapped[:wrapper.NonceSize()], wrapped[wrapper.NonceSize():], cseKeyWrapAAD(iv, size))
+	if err != nil {
+		return nil, errors.New("cannot decrypt data key, wrong client encryption key or passphrase")
+	}
+
+	aead, err := newGCM(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return &cseObject{aead: aead, iv: iv, size: size}, nil
+}
+
+// number of segments, empty objects have a single empty segment
+func (o *cseObject) segments() int64 {
+	if o.size == 0 {
+		return 1
+	}
+	return (o.size + cseSegmentSize - 1) / cseSegmentSize
+}
+
+// size of encrypted object
+func (o *cseObject) encryptedSize() int64 {
+	return o.size + o.segments()*int64(o.aead.Overhead())
+}
+
+// plaintext size of segment i
+func (o *cseObject) segmentSize(i int64) int64 {
+	return min(cseSegmentSize, o.size-i*cseSegmentSize)
+}
+
+func (o *cseObject) nonce(i int64) []byte {
+	nonce := make([]byte, len(o.iv))
+	copy(nonce, o.iv)
+	binary.BigEndian.PutUint32(nonce[len(nonce)-4:], binary.BigEndian.Uint32(nonce[len(nonce)-4:])^uint32(i))
+	return nonce
+}
+
+func (o *cseObject) aad(i int64) []byte {
+	if i == o.segments()-1 {
+		return []byte{1}
+	}
+	return []byte{0}
+}
+
+// encryptedRange returns the encrypted byte range holding plaintext range [start, end] and index of its first
+// segment, for ReadRange
+func (o *cseObject) encryptedRange(start, end int64) (encStart, encEnd, segment int64) {
+	encSegment := int64(cseSegmentSize + o.aead.Overhead())
+	first, last := start/cseSegmentSize, min(end/cseSegmentSize, o.segments()-1)
+	return first * encSegment, min((last+1)*encSegment, o.encryptedSize()) - 1, first
+}
+
+// encryptingReader encrypts a seekable plaintext on the fly, it is seekable itself so that the SDK can
+// compute checksums and retry requests
+type encryptingReader struct {
+	obj *cseObject
+	src io.ReadSeeker
+	// position in encrypted stream
+	pos int64
+	// index of segment in buf, -1 when buf is empty
+	segment int64
+	buf     []byte
+	plain   []byte
+}
+
+func newEncryptingReader(obj *cseObject, src io.ReadSeeker) *encryptingReader {
+	return &encryptingReader{obj: obj, src: src, segment: -1, plain: make([]byte, cseSegmentSize)}
+}
+
+func (r *encryptingReader) Read(p []byte) (int, error) {
+	if r.pos >= r.obj.encryptedSize() {
+		return 0, io.EOF
+	}
+
+	encSegment := int64(cseSegmentSize + r.obj.aead.Overhead())
+	i := r.pos / encSegment
+	if i != r.segment {
+		if _, err := r.src.Seek(i*cseSegmentSize, io.SeekStart); err != nil {
+			return 0, err
+		}
+		plain := r.plain[:r.obj.segmentSize(i)]
+		if _, err := io.ReadFull(r.src, plain); err != nil {
+			return 0, fmt.Errorf("cannot read segment %d, file changed while uploading? %w", i, err)
+		}
+		r.buf = r.obj.aead.Seal(r.buf[:0], r.obj.nonce(i), plain, r.obj.aad(i))
+		r.segment = i
+	}
+
+	n := copy(p, r.buf[r.pos-i*encSegment:])
+	r.pos += int64(n)
+	return n, nil
+}
+
+func (r *encryptingReader) Seek(offset int64, whence int) (int64, error) {
+	var pos int64
+	switch whence {
+	case io.SeekStart:
+		pos = offset
+	case io.SeekCurrent:
+		pos = r.pos + offset
+	case io.SeekEnd:
+		pos = r.obj.encryptedSize() + offset
+	default:
+		return r.pos, errors.New("invalid whence")
+	}
+	if pos < 0 {
+		return r.pos, errors.New("negative position")
+	}
+	r.pos = pos
+	return pos, nil
+}
+
+// decryptingReader decrypts an encrypted stream starting at the given segment
+type decryptingReader struct {
+	obj     *cseObject
+	src     io.Reader
+	segment int64
+	// decrypted bytes not read yet
+	buf []byte
+	enc []byte
+}
+
+func newDecryptingReader(obj *cseObject, src io.Reader, segment int64) *decryptingReader {
+	return &decryptingReader{obj: obj, src: src, segment: segment, enc: make([]byte, cseSegmentSize+obj.aead.Overhead())}
+}
+
+func (r *decryptingReader) Read(p []byte) (int, error) {
+	for len(r.buf) == 0 {
+		if r.segment >= r.obj.segments() {
+			return 0, io.EOF
+		}
+
+		enc := r.enc[:r.obj.segmentSize(r.segment)+int64(r.obj.aead.Overhead())]
+		if _, err := io.ReadFull(r.src, enc); err != nil {
+			if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+				return 0, fmt.Errorf("encrypted object is truncated at segment %d", r.segment)
+			}
+			return 0, err
+		}
+
+		plain, err := r.obj.aead.Open(enc[:0], r.obj.nonce(r.segment), enc, r.obj.aad(r.segment))
+		if err != nil {
+			return 0, fmt.Errorf("cannot decrypt segment %d, object is corrupted or tampered", r.segment)
+		}
+		r.buf = plain
+		r.segment++
+	}
+
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
@@ -0,0 +1,193 @@
+package s3transfer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/kullanici0606/s3cli/v2/internal/s3fake"
+	"github.com/stretchr/testify/require"
+)
+
+func randomData(t *testing.T, n int) []byte {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	require.NoError(t, err)
+	return b
+}
+
+func encrypt(t *testing.T, ce *ClientEncryption, plain []byte) (*cseObject, map[string]string, []byte) {
+	obj, metadata, err := ce.newObject(int64(len(plain)))
+	require.NoError(t, err)
+
+	enc, err := io.ReadAll(newEncryptingReader(obj, bytes.NewReader(plain)))
+	require.NoError(t, err)
+	require.Equal(t, obj.encryptedSize(), int64(len(enc)))
+	return obj, metadata, enc
+}
+
+func TestClientEncryptionRoundTrip(t *testing.T) {
+	ce, err := NewClientEncryption(bytes.Repeat([]byte{3}, 32))
+	require.NoError(t, err)
+
+	for _, size := range []int{0, 1, cseSegmentSize, cseSegmentSize + 1, 3*cseSegmentSize - 7} {
+		t.Run(fmt.Sprint(size), func(t *testing.T) {
+			plain := randomData(t, size)
+			_, metadata, enc := encrypt(t, ce, plain)
+
+			obj, err := ce.openObject(metadata)
+			require.NoError(t, err)
+			got, err := io.ReadAll(newDecryptingReader(obj, bytes.NewReader(enc), 0))
+			require.NoError(t, err)
+			require.Equal(t, plain, got)
+		})
+	}
+}
+
+func TestClientEncryptionDetectsTampering(t *testing.T) {
+	ce, err := NewClientEncryption(bytes.Repeat([]byte{3}, 32))
+	require.NoError(t, err)
+	plain := randomData(t, 2*cseSegmentSize+10)
+	obj, _, enc := encrypt(t, ce, plain)
+
+	flipped := bytes.Clone(enc)
+	flipped[100] ^= 1
+
+	cases := []struct {
+		name  string
+		input []byte
+	}{
+		{"flipped bit", flipped},
+		{"truncated", enc[:len(enc)-20]},
+		{"last segment dropped", enc[:2*(cseSegmentSize+obj.aead.Overhead())]},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, err := io.ReadAll(newDecryptingReader(obj, bytes.NewReader(c.input), 0))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestClientEncryptionKeyBoundToObject(t *testing.T) {
+	ce, err := NewClientEncryption(bytes.Repeat([]byte{3}, 32))
+	require.NoError(t, err)
+	_, metadata, _ := encrypt(t, ce, randomData(t, 100))
+	_, other, _ := encrypt(t, ce, randomData(t, 100))
+
+	cases := []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{"size", cseMetaSize, "99"},
+		{"iv of another object", cseMetaIV, other[cseMetaIV]},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tampered := maps.Clone(metadata)
+			tampered[c.key] = c.value
+			_, err := ce.openObject(tampered)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestClientEncryptionReadsRange(t *testing.T) {
+	ce, err := NewClientEncryption(bytes.Repeat([]byte{3}, 32))
+	require.NoError(t, err)
+	plain := randomData(t, 3*cseSegmentSize+100)
+	obj, _, enc := encrypt(t, ce, plain)
+
+	start, end := int64(cseSegmentSize+5), int64(2*cseSegmentSize+20)
+	encStart, encEnd, segment := obj.encryptedRange(start, end)
+
+	r := newDecryptingReader(obj, bytes.NewReader(enc[encStart:encEnd+1]), segment)
+	_, err = io.CopyN(io.Discard, r, start-segment*cseSegmentSize)
+	require.NoError(t, err)
+	got := make([]byte, end-start+1)
+	_, err = io.ReadFull(r, got)
+	require.NoError(t, err)
+	require.Equal(t, plain[start:end+1], got)
+}
+
+func TestEncryptingReaderSeek(t *testing.T) {
+	ce, err := NewClientEncryption(bytes.Repeat([]byte{3}, 32))
+	require.NoError(t, err)
+	plain := randomData(t, cseSegmentSize+10)
+	obj, _, enc := encrypt(t, ce, plain)
+
+	r := newEncryptingReader(obj, bytes.NewReader(plain))
+	_, err = r.Seek(int64(len(enc)-30), io.SeekStart)
+	require.NoError(t, err)
+	tail, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, enc[len(enc)-30:], tail)
+}
+
+func TestCopyWithClientEncryption(t *testing.T) {
+	passphrase, err := NewPassphraseEncryption("correct horse")
+	require.NoError(t, err)
+	passphrase.EncryptUploads = true
+
+	client, fake, _ := newFakeClient(Options{ClientEncryption: passphrase}, "bucket")
+	plain := randomData(t, cseSegmentSize+1)
+	src := filepath.Join(t.TempDir(), "data")
+	require.NoError(t, os.WriteFile(src, plain, 0644))
+
+	_, err = client.Copy(context.Background(), src, "s3://bucket/data")
+	require.NoError(t, err)
+
+	stored, _ := fake.Get("bucket", "data")
+	require.NotContains(t, string(stored), string(plain[:64]))
+	require.Contains(t, fake.Metadata("bucket", "data"), cseMetaKey)
+
+	dest := t.TempDir()
+	_, err = client.Copy(context.Background(), "s3://bucket/data", filepath.Join(dest, "data"))
+	require.NoError(t, err)
+	got, err := os.ReadFile(filepath.Join(dest, "data"))
+	require.NoError(t, err)
+	require.Equal(t, plain, got)
+
+	// a fresh client derives the key from the salt stored with the object
+	other, err := NewPassphraseEncryption("correct horse")
+	require.NoError(t, err)
+	_, err = New(fake, Options{ClientEncryption: other}).Copy(context.Background(), "s3://bucket/data", filepath.Join(dest, "again"))
+	require.NoError(t, err)
+
+	wrong, err := NewPassphraseEncryption("wrong")
+	require.NoError(t, err)
+	_, err = New(fake, Options{ClientEncryption: wrong}).Copy(context.Background(), "s3://bucket/data", filepath.Join(dest, "wrong"))
+	require.ErrorContains(t, err, "wrong client encryption key")
+
+	_, err = New(fake, Options{}).Copy(context.Background(), "s3://bucket/data", filepath.Join(dest, "nokey"))
+	require.ErrorIs(t, err, ErrNoClientKey)
+}
+
+func TestStreamedCopyKeepsMetadata(t *testing.T) {
+	src, dest := s3fake.New(), s3fake.New()
+	src.CreateBucket("bucket", false)
+	dest.CreateBucket("bucket", false)
+	_, err := src.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("key"),
+		Body:     strings.NewReader("data"),
+		Metadata: map[string]string{"owner": "team"},
+	})
+	require.NoError(t, err)
+
+	_, err = New(src, Options{Destination: dest}).Copy(context.Background(), "s3://bucket/key", "s3://bucket/copy")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"owner": "team"}, dest.Metadata("bucket", "copy"))
+}
@@ -0,0 +1,136 @@
+package s3transfer
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// length of SSE-C keys, S3 only supports AES256
+const customerKeyLen = 32
+
+// Encryption configures server side encryption, zero value leaves it to the bucket defaults
+type Encryption struct {
+	// encryption of written objects, AES256 or aws:kms
+	Algorithm types.ServerSideEncryption
+	// KMS key of aws:kms encryption, empty uses the AWS managed key
+	KMSKeyID string
+	// use an S3 bucket key for aws:kms encryption, nil uses the bucket setting
+	BucketKeyEnabled *bool
+	// key for SSE-C, objects are written and read with it
+	CustomerKey []byte
+}
+
+// Validate checks that the settings can be used together
+func (e Encryption) Validate() error {
+	switch e.Algorithm {
+	case "", types.ServerSideEncryptionAes256, types.ServerSideEncryptionAwsKms:
+	default:
+		return errors.New("sse should be AES256 or aws:kms")
+	}
+
+	if e.Algorithm != types.ServerSideEncryptionAwsKms && (len(e.KMSKeyID) != 0 || e.BucketKeyEnabled != nil) {
+		return errors.New("kms key id and bucket key require aws:kms encryption")
+	}
+
+	if e.CustomerKey != nil {
+		if len(e.CustomerKey) != customerKeyLen {
+			return errors.New("sse-c key should be 256 bits")
+		}
+		if len(e.Algorithm) != 0 {
+			return errors.New("sse-c cannot be used with sse")
+		}
+	}
+	return nil
+}
+
+// SSE-C request parameters, all nil without a customer key
+func (e Encryption) customerKey() (algorithm, key, keyMD5 *string) {
+	if e.CustomerKey == nil {
+		return nil, nil, nil
+	}
+
+	sum := md5.Sum(e.CustomerKey)
+	return stringOrNil(string(types.ServerSideEncryptionAes256)),
+		stringOrNil(base64.StdEncoding.EncodeToString(e.CustomerKey)),
+		stringOrNil(base64.StdEncoding.EncodeToString(sum[:]))
+}
+
+func (e Encryption) applyToPut(input *s3.PutObjectInput) {
+	input.ServerSideEncryption = e.Algorithm
+	input.SSEKMSKeyId = stringOrNil(e.KMSKeyID)
+	input.BucketKeyEnabled = e.BucketKeyEnabled
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = e.customerKey()
+}
+
+func (e Encryption) applyToCreateMultipart(input *s3.CreateMultipartUploadInput) {
+	input.ServerSideEncryption = e.Algorithm
+	input.SSEKMSKeyId = stringOrNil(e.KMSKeyID)
+	input.BucketKeyEnabled = e.BucketKeyEnabled
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = e.customerKey()
+}
+
+// parts of SSE-C uploads are sent with the key of the upload
+func (e Encryption) applyToUploadPart(input *s3.UploadPartInput) {
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = e.customerKey()
+}
+
+// the copy is encrypted with the same settings and an SSE-C source is read with the same key, S3 rejects
+// source keys of other objects
+func (e Encryption) applyToCopy(input *s3.CopyObjectInput, sourceSSEC bool) {
+	input.ServerSideEncryption = e.Algorithm
+	input.SSEKMSKeyId = stringOrNil(e.KMSKeyID)
+	input.BucketKeyEnabled = e.BucketKeyEnabled
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = e.customerKey()
+	if sourceSSEC {
+		input.CopySourceSSECustomerAlgorithm, input.CopySourceSSECustomerKey, input.CopySourceSSECustomerKeyMD5 = e.customerKey()
+	}
+}
+
+// parts are written with the key of the upload, parts copied from an SSE-C source are read with the same key
+func (e Encryption) applyToUploadPartCopy(input *s3.UploadPartCopyInput, sourceSSEC bool) {
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = e.customerKey()
+	if sourceSSEC {
+		input.CopySourceSSECustomerAlgorithm, input.CopySourceSSECustomerKey, input.CopySourceSSECustomerKeyMD5 = e.customerKey()
+	}
+}
+
+func (e Encryption) applyToGet(input *s3.GetObjectInput) {
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = e.customerKey()
+}
+
+func (e Encryption) applyToHead(input *s3.HeadObjectInput) {
+	input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = e.customerKey()
+}
+
+// attributes of SSE-C objects are read with the key, S3 rejects keys of other objects
+func (e Encryption) applyToGetAttributes(input *s3.GetObjectAttributesInput, ssec bool) {
+	if ssec {
+		input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = e.customerKey()
+	}
+}
+
+// whether an object is SSE-C encrypted, by the algorithm S3 returns for reads of such objects
+func isCustomerEncrypted(algorithm *string) bool {
+	return len(aws.ToString(algorithm)) != 0
+}
+
+// isCustomerKeyError reports whether err is S3 refusing a request for the SSE-C parameters it was sent, as for
+// reading objects that are not SSE-C encrypted with a key. HEAD responses have no body, so only their status
+// is known.
+func isCustomerKeyError(err error) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	switch apiErr.ErrorCode() {
+	case "BadRequest", "InvalidRequest", "InvalidArgument":
+		return true
+	}
+	return false
+}
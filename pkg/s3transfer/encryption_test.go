@@ -0,0 +1,131 @@
+package s3transfer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptionValidate(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	cases := []struct {
+		name    string
+		input   Encryption
+		wantErr bool
+	}{
+		{"none", Encryption{}, false},
+		{"kms with key", Encryption{Algorithm: types.ServerSideEncryptionAwsKms, KMSKeyID: "alias/data", BucketKeyEnabled: aws.Bool(true)}, false},
+		{"customer key", Encryption{CustomerKey: key}, false},
+		{"unknown algorithm", Encryption{Algorithm: "DES"}, true},
+		{"kms key without kms", Encryption{Algorithm: types.ServerSideEncryptionAes256, KMSKeyID: "alias/data"}, true},
+		{"short customer key", Encryption{CustomerKey: key[:16]}, true},
+		{"customer key with sse", Encryption{Algorithm: types.ServerSideEncryptionAes256, CustomerKey: key}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.input.Validate()
+			if (err != nil) != c.wantErr {
+				t.Errorf("got %v want error %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCopyWithKMSEncryption(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{Encryption: Encryption{Algorithm: types.ServerSideEncryptionAwsKms, KMSKeyID: "alias/data"}}, "bucket")
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{"a": "a"})
+
+	_, err := client.Copy(context.Background(), filepath.Join(src, "a"), "s3://bucket/a")
+	require.NoError(t, err)
+	_, err = client.Copy(context.Background(), "s3://bucket/a", "s3://bucket/b")
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "b"} {
+		output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String(key)})
+		require.NoError(t, err)
+		require.Equal(t, types.ServerSideEncryptionAwsKms, output.ServerSideEncryption)
+		require.Equal(t, "alias/data", aws.ToString(output.SSEKMSKeyId))
+	}
+}
+
+func TestCopyWithCustomerKey(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{Encryption: Encryption{CustomerKey: bytes.Repeat([]byte{7}, 32)}}, "bucket")
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{"a": "secret"})
+
+	_, err := client.Copy(context.Background(), filepath.Join(src, "a"), "s3://bucket/a")
+	require.NoError(t, err)
+	_, err = client.Copy(context.Background(), "s3://bucket/a", "s3://bucket/b")
+	require.NoError(t, err)
+	require.NoError(t, client.uploadStream(context.Background(), "bucket", "multipart", strings.NewReader("0123456789"), 4, ObjectAttributes{}, writeCondition{}))
+
+	dest := t.TempDir()
+	for _, key := range []string{"a", "b", "multipart"} {
+		_, err = client.Copy(context.Background(), "s3://bucket/"+key, filepath.Join(dest, key))
+		require.NoError(t, err)
+	}
+	got, err := os.ReadFile(filepath.Join(dest, "b"))
+	require.NoError(t, err)
+	require.Equal(t, "secret", string(got))
+
+	// objects cannot be read without the key
+	withoutKey := New(fake, Options{})
+	_, err = withoutKey.Copy(context.Background(), "s3://bucket/a", filepath.Join(dest, "c"))
+	require.ErrorContains(t, err, "InvalidRequest")
+}
+
+func TestCopyIntoCustomerKey(t *testing.T) {
+	defer func(size, partSize int64) { maxCopyObjectSize, copyPartSize = size, partSize }(maxCopyObjectSize, copyPartSize)
+
+	cases := []struct {
+		name           string
+		maxCopySize    int64
+		overwrite      OverwritePolicy
+		existingDest   bool
+		wantPartCopies int
+	}{
+		{"copy object", 5 * 1024 * 1024 * 1024, OverwriteAlways, false, 0},
+		{"multipart copy", 8, OverwriteAlways, false, 3},
+		{"unencrypted destination checked", 5 * 1024 * 1024 * 1024, OverwriteIfDifferent, true, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			maxCopyObjectSize, copyPartSize = c.maxCopySize, 4
+			client, fake, _ := newFakeClient(Options{Overwrite: c.overwrite, Encryption: Encryption{CustomerKey: bytes.Repeat([]byte{7}, 32)}}, "bucket")
+			_, err := fake.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String("plain"), Body: strings.NewReader("0123456789")})
+			require.NoError(t, err)
+			if c.existingDest {
+				_, err = fake.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String("encrypted"), Body: strings.NewReader("old")})
+				require.NoError(t, err)
+			}
+
+			// an unencrypted source is read without the key and the copy is encrypted with it
+			result, err := client.Copy(context.Background(), "s3://bucket/plain", "s3://bucket/encrypted")
+			require.NoError(t, err)
+			require.Equal(t, int64(1), result.Completed)
+			require.Equal(t, c.wantPartCopies, fake.Calls("UploadPartCopy"))
+
+			output, err := client.Head(context.Background(), "bucket", "encrypted")
+			require.NoError(t, err)
+			require.Equal(t, string(types.ServerSideEncryptionAes256), aws.ToString(output.SSECustomerAlgorithm))
+
+			dest := filepath.Join(t.TempDir(), "encrypted")
+			_, err = client.Copy(context.Background(), "s3://bucket/encrypted", dest)
+			require.NoError(t, err)
+			got, err := os.ReadFile(dest)
+			require.NoError(t, err)
+			require.Equal(t, "0123456789", string(got))
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package s3transfer
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+// Operation is the kind of work done on an object
+type Operation string
+
+const (
+	OpUpload   Operation = "upload"
+	OpDownload Operation = "download"
+	OpCopy     Operation = "copy"
+	OpDelete   Operation = "delete"
+	OpRestore  Operation = "restore"
+	OpTag      Operation = "tag"
+)
+
+// Status is the outcome of an operation
+type Status string
+
+const (
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	// object was left alone on purpose, i.e. archived objects when skipping them
+	StatusSkipped Status = "skipped"
+)
+
+// Event reports the outcome of an operation on a single object
+type Event struct {
+	Op Operation
+	// local path or s3 path the object is read from, s3 path of the object for deletes
+	Source string
+	// local path or s3 path the object is written to, empty for deletes
+	Destination string
+	// size of the transferred object, 0 for deletes
+	Bytes    int64
+	Duration time.Duration
+	Status   Status
+	// cause of the failure or reason of the skip, nil when the operation succeeded
+	Err error
+}
+
+// event for an operation started at start, failed when err is not nil
+func newEvent(op Operation, src, dest string, bytes int64, start time.Time, err error) Event {
+	e := Event{
+		Op:          op,
+		Source:      src,
+		Destination: dest,
+		Bytes:       bytes,
+		Duration:    time.Since(start),
+		Status:      StatusSucceeded,
+		Err:         err,
+	}
+	var skipped *SkippedError
+	switch {
+	case errors.As(err, &skipped):
+		e.Status = StatusSkipped
+	case err != nil:
+		e.Status = StatusFailed
+	}
+	return e
+}
+
+// SkippedError is the reason an object was skipped, it does not fail the run
+type SkippedError struct {
+	Err error
+}
+
+func (e *SkippedError) Error() string {
+	return "skipped: " + e.Err.Error()
+}
+
+func (e *SkippedError) Unwrap() error {
+	return e.Err
+}
+
+// error failing the run, nil for skipped objects
+func failure(err error) error {
+	var skipped *SkippedError
+	if errors.As(err, &skipped) {
+		return nil
+	}
+	return err
+}
+
+// DeleteError is reported for objects S3 refused to delete
+type DeleteError struct {
+	Code    string
+	Message string
+}
+
+func (e *DeleteError) Error() string {
+	return e.Message
+}
+
+// Result summarizes a Copy or Remove run
+type Result struct {
+	// objects whose operation succeeded
+	Completed int64
+	// objects whose operation failed
+	Failed int64
+	// objects skipped on purpose, and queued objects or globs that were not processed because the run was cancelled
+	Skipped int64
+	// bytes of objects whose operation succeeded
+	Bytes int64
+}
+
+// Add adds counts of other to r, for summarizing several runs
+func (r *Result) Add(other Result) {
+	r.Completed += other.Completed
+	r.Failed += other.Failed
+	r.Skipped += other.Skipped
+	r.Bytes += other.Bytes
+}
+
+// report event to OnEvent and count it in result, all events of a run pass through here
+func (c *Client) report(result *Result, e Event) {
+	switch e.Status {
+	case StatusSkipped:
+		result.Skipped++
+	case StatusFailed:
+		result.Failed++
+	default:
+		result.Completed++
+		result.Bytes += e.Bytes
+	}
+
+	if c.opts.OnEvent != nil {
+		c.opts.OnEvent(e)
+	}
+}
+
+// Progress receives progress of transfers, implementations must be safe for concurrent use
+type Progress interface {
+	// AddTotal registers an object found while listing or walking directories
+	AddTotal(size int64)
+	// StartFile is called when transfer of an object starts
+	StartFile(name string, size int64) FileProgress
+}
+
+// FileProgress receives progress of a single transfer
+type FileProgress interface {
+	// AddBytes reports transferred bytes, n is negative when a body is rewound
+	AddBytes(n int64)
+	// Finish is called when the transfer ends, successfully or not
+	Finish()
+}
+
+type noProgress struct{}
+
+func (noProgress) AddTotal(size int64)                            {}
+func (noProgress) StartFile(name string, size int64) FileProgress { return noProgress{} }
+func (noProgress) AddBytes(n int64)                               {}
+func (noProgress) Finish()                                        {}
+
+// progressReader reports bytes read through it to the progress of a file
+type progressReader struct {
+	r    io.Reader
+	fp   FileProgress
+	read int64
+}
+
+func (pr *progressReader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	pr.read += int64(n)
+	pr.fp.AddBytes(int64(n))
+	return n, err
+}
+
+// progressReadSeeker is a progressReader over a seekable stream. The SDK reads seekable bodies once for
+// computing checksums and rewinds them, so seeking moves the reported bytes along with the position.
+type progressReadSeeker struct {
+	progressReader
+}
+
+func (prs *progressReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := prs.r.(io.Seeker).Seek(offset, whence)
+	if err != nil {
+		return pos, err
+	}
+	prs.fp.AddBytes(pos - prs.read)
+	prs.read = pos
+	return pos, nil
+}
+
+// wrap reader so that bytes read are reported to fp, keeping it seekable if it was
+func wrapReader(r io.Reader, fp FileProgress) io.Reader {
+	pr := progressReader{r: r, fp: fp}
+	if _, ok := r.(io.Seeker); ok {
+		return &progressReadSeeker{pr}
+	}
+	return &pr
+}
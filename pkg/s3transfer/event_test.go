@@ -0,0 +1,133 @@
+package s3transfer
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/require"
+)
+
+type countingFileProgress struct {
+	bytes    int64
+	finished bool
+}
+
+func (fp *countingFileProgress) AddBytes(n int64) { fp.bytes += n }
+func (fp *countingFileProgress) Finish()          { fp.finished = true }
+
+func TestProgressReaderRewind(t *testing.T) {
+	fp := &countingFileProgress{}
+	r := wrapReader(strings.NewReader("0123456789"), fp)
+
+	seeker, ok := r.(io.ReadSeeker)
+	require.True(t, ok, "seekable reader should stay seekable")
+
+	// read whole body as the SDK does while computing checksums, then rewind and send
+	_, err := io.ReadAll(seeker)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), fp.bytes)
+
+	_, err = seeker.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), fp.bytes)
+
+	_, err = io.CopyN(io.Discard, seeker, 4)
+	require.NoError(t, err)
+	require.Equal(t, int64(4), fp.bytes)
+}
+
+func TestProgressReaderNotSeekable(t *testing.T) {
+	fp := &countingFileProgress{}
+	r := wrapReader(io.MultiReader(strings.NewReader("0123456789")), fp)
+
+	_, ok := r.(io.Seeker)
+	require.False(t, ok)
+
+	_, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, int64(10), fp.bytes)
+}
+
+// recordingProgress counts files started and finished by name
+type recordingProgress struct {
+	mu       sync.Mutex
+	started  map[string]int
+	finished map[string]int
+}
+
+type recordingFileProgress struct {
+	p    *recordingProgress
+	name string
+}
+
+func (p *recordingProgress) AddTotal(size int64) {}
+
+func (p *recordingProgress) StartFile(name string, size int64) FileProgress {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.started[name]++
+	return recordingFileProgress{p: p, name: name}
+}
+
+func (fp recordingFileProgress) AddBytes(n int64) {}
+
+func (fp recordingFileProgress) Finish() {
+	fp.p.mu.Lock()
+	defer fp.p.mu.Unlock()
+	fp.p.finished[fp.name]++
+}
+
+func TestProgressOncePerObject(t *testing.T) {
+	cases := []struct {
+		name      string
+		opts      Options
+		archived  bool
+		existing  bool
+		dest      string
+		wantName  string
+		wantEvent Status
+	}{
+		{"restored copy", Options{Archived: ArchivedWait}, true, false, "s3://bucket/b", "s3://bucket/a", StatusSucceeded},
+		{"restored download", Options{Archived: ArchivedWait}, true, false, "a", "a", StatusSucceeded},
+		{"skipped copy", Options{Overwrite: OverwriteNever}, false, true, "s3://bucket/b", "s3://bucket/a", StatusSkipped},
+		{"skipped download", Options{Overwrite: OverwriteNever}, false, true, "a", "a", StatusSkipped},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			progress := &recordingProgress{started: map[string]int{}, finished: map[string]int{}}
+			c.opts.Progress = progress
+			c.opts.RestorePollInterval = 5 * time.Millisecond
+			client, fake, recorder := newFakeClient(c.opts, "bucket")
+			fake.RestoreDuration = 10 * time.Millisecond
+			class := types.StorageClassStandard
+			if c.archived {
+				class = types.StorageClassGlacier
+			}
+			putArchived(t, fake, "bucket", map[string]types.StorageClass{"a": class})
+
+			dest := c.dest
+			if !IsS3Path(dest) {
+				dest = filepath.Join(t.TempDir(), dest)
+				if c.existing {
+					require.NoError(t, os.WriteFile(dest, []byte("old"), 0644))
+				}
+			} else if c.existing {
+				putArchived(t, fake, "bucket", map[string]types.StorageClass{"b": types.StorageClassStandard})
+			}
+
+			_, err := client.Copy(context.Background(), "s3://bucket/a", dest)
+			require.NoError(t, err)
+			require.Equal(t, c.wantEvent, recorder.events[0].Status)
+			require.Equal(t, map[string]int{c.wantName: 1}, progress.started)
+			require.Equal(t, map[string]int{c.wantName: 1}, progress.finished)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package s3transfer
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// ListParams selects objects to list, empty Prefix lists the whole bucket and empty Delimiter lists recursively
+type ListParams struct {
+	Bucket    string
+	Prefix    string
+	Delimiter string
+	// only objects having all of these tags are listed, tags are fetched for each listed object
+	Tags []TagFilter
+}
+
+// List calls onList with each page of objects matching params, stops early when ctx is cancelled
+func (c *Client) List(ctx context.Context, params ListParams, onList func(*s3.ListObjectsV2Output)) error {
+	isTruncated := true
+	var continuationToken *string
+
+loop:
+	for isTruncated {
+		select {
+		case <-ctx.Done():
+			break loop
+		default:
+			// do nothing
+		}
+
+		output, err := c.listObjectsPage(ctx, &s3.ListObjectsV2Input{
+			Bucket:            aws.String(params.Bucket),
+			Prefix:            stringOrNil(params.Prefix),
+			Delimiter:         stringOrNil(params.Delimiter),
+			ContinuationToken: continuationToken,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		if *output.IsTruncated {
+			slog.Debug("s3 list pagination", "token", aws.ToString(output.NextContinuationToken), "isTruncated", *output.IsTruncated)
+		}
+
+		if len(params.Tags) != 0 {
+			if output.Contents, err = c.filterByTags(ctx, params.Bucket, output.Contents, params.Tags); err != nil {
+				return err
+			}
+		}
+
+		onList(output)
+
+		isTruncated = *output.IsTruncated
+		continuationToken = output.NextContinuationToken
+	}
+
+	return nil
+}
+
+func (c *Client) listObjectsPage(ctx context.Context, input *s3.ListObjectsV2Input) (*s3.ListObjectsV2Output, error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	return c.api.ListObjectsV2(ctx, input)
+}
+
+// call fn with each object at s3 paths until it returns false, paths ending with * select objects under the
+// prefix accepted by filter, nil filter accepts all
+func (c *Client) forEachObject(ctx context.Context, paths []string, filter func(types.Object) bool, fn func(bucket, key string) bool) error {
+	globs, regulars := splitGlobsAndRegulars(paths)
+	for _, p := range regulars {
+		bucket, key, err := ParsePath(p)
+		if err != nil {
+			return err
+		}
+		if !fn(bucket, key) {
+			return nil
+		}
+	}
+
+	for _, p := range globs {
+		bucket, prefix, err := ParsePath(strings.TrimSuffix(p, "*"))
+		if err != nil {
+			return err
+		}
+
+		stop := false
+		err = c.List(ctx, ListParams{Bucket: bucket, Prefix: prefix}, func(output *s3.ListObjectsV2Output) {
+			for _, o := range output.Contents {
+				if stop || (filter != nil && !filter(o)) {
+					continue
+				}
+				stop = !fn(bucket, aws.ToString(o.Key))
+			}
+		})
+		if err != nil || stop {
+			return err
+		}
+	}
+	return nil
+}
+
+// run fn in the worker pool for each object selected as in forEachObject, stops at the first error
+func (c *Client) runForEachObject(ctx context.Context, paths []string, filter func(types.Object) bool, fn func(ctx context.Context, bucket, key string) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fnch := make(chan func() error, c.opts.Concurrency)
+	errch := make(chan error, 1)
+	go func() {
+		err := c.runWithErrgroup(ctx, fnch, &poolCounters{})
+		if err != nil {
+			cancel()
+		}
+		errch <- err
+	}()
+	work, cancelWork := inFlightContext(ctx)
+	defer cancelWork()
+
+	err := c.forEachObject(ctx, paths, filter, func(bucket, key string) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case fnch <- func() error {
+			return fn(work, bucket, key)
+		}:
+			return true
+		}
+	})
+
+	close(fnch)
+	if werr := <-errch; werr != nil {
+		return werr
+	}
+	return err
+}
+
+// Head returns metadata of a single object
+func (c *Client) Head(ctx context.Context, bucket, key string) (*s3.HeadObjectOutput, error) {
+	return c.head(ctx, bucket, key, false)
+}
+
+// head object, with its additional checksums when checksums is set. Objects that are not SSE-C encrypted are
+// headed again without the customer key S3 rejects for them.
+func (c *Client) head(ctx context.Context, bucket, key string, checksums bool) (*s3.HeadObjectOutput, error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	input := &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	c.opts.Encryption.applyToHead(input)
+	if checksums {
+		input.ChecksumMode = types.ChecksumModeEnabled
+	}
+	output, err := c.api.HeadObject(ctx, input)
+	if err != nil && input.SSECustomerKey != nil && isCustomerKeyError(err) {
+		input.SSECustomerAlgorithm, input.SSECustomerKey, input.SSECustomerKeyMD5 = nil, nil, nil
+		return c.api.HeadObject(ctx, input)
+	}
+	return output, err
+}
+
+// optional request parameter, nil when s is empty
+func stringOrNil(s string) *string {
+	if len(s) == 0 {
+		return nil
+	}
+	return aws.String(s)
+}
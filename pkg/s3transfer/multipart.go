@@ -0,0 +1,261 @@
+package s3transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	minPartSize    = 16 * 1024 * 1024
+	maxUploadParts = 10000
+)
+
+// size of parts copied with UploadPartCopy, larger than uploaded parts as copies do not pass through memory
+var copyPartSize int64 = 512 * 1024 * 1024
+
+// part size for uploading an object of given size without exceeding the maximum number of parts
+func partSizeFor(size int64) int64 {
+	partSize := int64(minPartSize)
+	if size > partSize*maxUploadParts {
+		partSize = (size + maxUploadParts - 1) / maxUploadParts
+	}
+	return partSize
+}
+
+// upload contents of r to bucket/key keeping at most one part in memory.
+// Streams fitting in a single part are uploaded with PutObject, others with multipart upload.
+// The object is only written when cond holds.
+func (c *Client) uploadStream(ctx context.Context, bucket, key string, r io.Reader, partSize int64, attrs ObjectAttributes, cond writeCondition) error {
+	buf := make([]byte, partSize)
+	n, err := io.ReadFull(r, buf)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		ctx, cancel := c.requestContext(ctx)
+		defer cancel()
+
+		input := &s3.PutObjectInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+			Body:   c.bandwidth.wrapUpload(ctx, bytes.NewReader(buf[:n])),
+		}
+		attrs.applyToPut(input)
+		c.opts.Storage.applyToPut(input)
+		c.opts.Encryption.applyToPut(input)
+		c.applyChecksumToPut(input)
+		_, err = c.api.PutObject(ctx, input, cond.options()...)
+		return cond.check(err)
+	}
+	if err != nil {
+		return err
+	}
+
+	createCtx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	attrs.applyToCreateMultipart(input)
+	c.opts.Storage.applyToCreateMultipart(input)
+	c.opts.Encryption.applyToCreateMultipart(input)
+	// S3 computes checksum of the object from checksums of its parts
+	input.ChecksumAlgorithm = c.opts.Checksum
+	upload, err := c.api.CreateMultipartUpload(createCtx, input)
+	if err != nil {
+		return err
+	}
+
+	slog.Debug("multipart upload started", "bucket", bucket, "key", key, "upload_id", aws.ToString(upload.UploadId))
+
+	err = c.uploadParts(ctx, upload, r, buf, cond)
+	if err != nil {
+		c.abortUpload(ctx, upload)
+		return err
+	}
+	return nil
+}
+
+// do not leave incomplete uploads behind, they are charged for storage.
+// abort is sent even if ctx is cancelled, as cancellation is the usual reason of failure
+func (c *Client) abortUpload(ctx context.Context, upload *s3.CreateMultipartUploadOutput) {
+	ctx, cancel := c.requestContext(context.WithoutCancel(ctx))
+	defer cancel()
+
+	_, err := c.api.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+		Bucket:   upload.Bucket,
+		Key:      upload.Key,
+		UploadId: upload.UploadId,
+	})
+	if err != nil {
+		slog.Warn("cannot abort multipart upload", "bucket", aws.ToString(upload.Bucket), "key", aws.ToString(upload.Key), "upload_id", aws.ToString(upload.UploadId), "error", err)
+	}
+}
+
+// upload parts of multipart upload starting with the part already read into buf
+func (c *Client) uploadParts(ctx context.Context, upload *s3.CreateMultipartUploadOutput, r io.Reader, buf []byte, cond writeCondition) error {
+	var completed []types.CompletedPart
+	n := len(buf)
+	for partNumber := int32(1); n > 0; partNumber++ {
+		input := &s3.UploadPartInput{
+			Bucket:     upload.Bucket,
+			Key:        upload.Key,
+			UploadId:   upload.UploadId,
+			PartNumber: aws.Int32(partNumber),
+			Body:       c.bandwidth.wrapUpload(ctx, bytes.NewReader(buf[:n])),
+		}
+		c.applyChecksumToUploadPart(input)
+		output, err := c.uploadPart(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		part := types.CompletedPart{
+			ETag:       output.ETag,
+			PartNumber: aws.Int32(partNumber),
+		}
+		c.applyChecksumToCompletedPart(&part, output)
+		completed = append(completed, part)
+
+		n, err = io.ReadFull(r, buf)
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return err
+		}
+	}
+
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	_, err := c.api.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          upload.Bucket,
+		Key:             upload.Key,
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	}, cond.options()...)
+	return cond.check(err)
+}
+
+func (c *Client) uploadPart(ctx context.Context, input *s3.UploadPartInput) (*s3.UploadPartOutput, error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	c.opts.Encryption.applyToUploadPart(input)
+	return c.api.UploadPart(ctx, input)
+}
+
+// copy object of size to bucket/key server side with UploadPartCopy, for objects too large for CopyObject.
+// Attributes and tags are kept as CopyObject does, the object is only written when cond holds.
+func (c *Client) copyObjectMultipart(ctx context.Context, srcBucket, srcKey, rel string, size int64, bucket, key string, cond writeCondition, fp FileProgress) error {
+	head, err := c.Head(ctx, srcBucket, srcKey)
+	if err != nil {
+		return err
+	}
+
+	attrs := headAttributes(head)
+	if c.opts.MetadataDirective == types.MetadataDirectiveReplace {
+		attrs = c.copyAttributes(rel, head.Metadata)
+	}
+	if len(c.opts.Storage.Tagging) == 0 {
+		tags, err := c.getTags(ctx, srcBucket, srcKey)
+		if err != nil {
+			return err
+		}
+		attrs.tagging = encodeTagging(tags)
+	}
+
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	}
+	attrs.applyToCreateMultipart(input)
+	c.opts.Storage.applyToCreateMultipart(input)
+	c.opts.Encryption.applyToCreateMultipart(input)
+	input.ChecksumAlgorithm = c.opts.Checksum
+	// parts are copied from the version that was headed, a source changed meanwhile fails the copy
+	return c.multipartCopy(ctx, input, copySource(srcBucket, srcKey), head, cond, fp)
+}
+
+// create multipart upload of input and copy the headed source into it
+func (c *Client) multipartCopy(ctx context.Context, input *s3.CreateMultipartUploadInput, source string, head *s3.HeadObjectOutput, cond writeCondition, fp FileProgress) error {
+	createCtx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	upload, err := c.api.CreateMultipartUpload(createCtx, input)
+	if err != nil {
+		return err
+	}
+
+	slog.Debug("multipart copy started", "bucket", aws.ToString(input.Bucket), "key", aws.ToString(input.Key), "upload_id", aws.ToString(upload.UploadId))
+
+	err = c.copyParts(ctx, upload, source, head, fp, cond)
+	if err != nil {
+		c.abortUpload(ctx, upload)
+		return err
+	}
+	return nil
+}
+
+// copy parts of multipart upload from the headed source and complete the upload
+func (c *Client) copyParts(ctx context.Context, upload *s3.CreateMultipartUploadOutput, source string, head *s3.HeadObjectOutput, fp FileProgress, cond writeCondition) error {
+	etag, size := aws.ToString(head.ETag), aws.ToInt64(head.ContentLength)
+	partSize := copyPartSize
+	if size > partSize*maxUploadParts {
+		partSize = (size + maxUploadParts - 1) / maxUploadParts
+	}
+
+	var completed []types.CompletedPart
+	for partNumber, offset := int32(1), int64(0); offset < size; partNumber, offset = partNumber+1, offset+partSize {
+		last := min(offset+partSize, size) - 1
+		input := &s3.UploadPartCopyInput{
+			Bucket:            upload.Bucket,
+			Key:               upload.Key,
+			UploadId:          upload.UploadId,
+			PartNumber:        aws.Int32(partNumber),
+			CopySource:        aws.String(source),
+			CopySourceRange:   aws.String("bytes=" + strconv.FormatInt(offset, 10) + "-" + strconv.FormatInt(last, 10)),
+			CopySourceIfMatch: aws.String(etag),
+		}
+		c.opts.Encryption.applyToUploadPartCopy(input, isCustomerEncrypted(head.SSECustomerAlgorithm))
+		result, err := c.uploadPartCopy(ctx, input)
+		if err != nil {
+			return err
+		}
+
+		completed = append(completed, types.CompletedPart{
+			ETag:           result.ETag,
+			PartNumber:     aws.Int32(partNumber),
+			ChecksumCRC32:  result.ChecksumCRC32,
+			ChecksumCRC32C: result.ChecksumCRC32C,
+			ChecksumSHA1:   result.ChecksumSHA1,
+			ChecksumSHA256: result.ChecksumSHA256,
+		})
+		fp.AddBytes(last + 1 - offset)
+	}
+
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	_, err := c.api.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          upload.Bucket,
+		Key:             upload.Key,
+		UploadId:        upload.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	}, cond.options()...)
+	return cond.check(err)
+}
+
+func (c *Client) uploadPartCopy(ctx context.Context, input *s3.UploadPartCopyInput) (*types.CopyPartResult, error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+	output, err := c.api.UploadPartCopy(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return output.CopyPartResult, nil
+}
@@ -0,0 +1,360 @@
+package s3transfer
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// OverwritePolicy selects which existing files and objects copies overwrite
+type OverwritePolicy string
+
+const (
+	// overwrite existing destinations
+	OverwriteAlways OverwritePolicy = ""
+	// never overwrite existing destinations
+	OverwriteNever OverwritePolicy = "never"
+	// overwrite destinations modified before the source
+	OverwriteIfNewer OverwritePolicy = "if-newer"
+	// overwrite destinations whose size or contents differ from the source
+	OverwriteIfDifferent OverwritePolicy = "if-different"
+)
+
+var (
+	// ErrDestinationExists is the reason of copies skipped by OverwriteNever
+	ErrDestinationExists = errors.New("destination exists")
+	// ErrDestinationUpToDate is the reason of copies skipped by OverwriteIfNewer and OverwriteIfDifferent
+	ErrDestinationUpToDate = errors.New("destination is up to date")
+	// ErrDestinationChanged is the reason of uploads skipped because another writer changed the destination
+	// after it was checked
+	ErrDestinationChanged = errors.New("destination was changed by another writer")
+)
+
+// size, modification time and contents of a copy source or destination compared by overwrite policies
+type version struct {
+	size    int64
+	modTime time.Time
+	// hex md5 of contents, nil or empty when unknown. Only called when sizes are equal, as it may read the file
+	md5 func() (string, error)
+	// contents are of a local file and reading digests reads the file
+	local bool
+	// additional checksum stored with an object, zero for files and objects without checksums
+	stored storedChecksum
+	// checksum of contents computed as stored checksum s of the other side, nil or empty when it cannot be.
+	// Only called when sizes are equal, as it may read the file
+	checksum func(s storedChecksum) (string, error)
+}
+
+// storedChecksum is an additional checksum S3 keeps with an object
+type storedChecksum struct {
+	algorithm types.ChecksumAlgorithm
+	// base64 checksum, composite checksums of multipart uploads end with the number of parts
+	value string
+	// sizes of parts composite checksums are computed over, nil for full object checksums
+	partSizes func() ([]int64, error)
+}
+
+// check whether dest is kept instead of being overwritten by src, returns SkippedError when it is kept
+func (p OverwritePolicy) check(src, dest version) error {
+	switch p {
+	case OverwriteNever:
+		return &SkippedError{Err: ErrDestinationExists}
+	case OverwriteIfNewer:
+		if !src.modTime.After(dest.modTime) {
+			return &SkippedError{Err: ErrDestinationUpToDate}
+		}
+	case OverwriteIfDifferent:
+		if src.size != dest.size {
+			return nil
+		}
+		// contents that cannot be compared are overwritten
+		same, err := sameContents(src, dest)
+		if err != nil || !same {
+			return err
+		}
+		return &SkippedError{Err: ErrDestinationUpToDate}
+	}
+	return nil
+}
+
+// whether versions of equal size have the same contents by their md5, or by the checksum one of them is stored
+// with. The object side decides which digest is compared, so a local file is read at most once and only for
+// a digest the object has.
+func sameContents(a, b version) (bool, error) {
+	if a.local {
+		a, b = b, a
+	}
+
+	if a.md5 != nil && b.md5 != nil {
+		aMD5, err := a.md5()
+		if err != nil || len(aMD5) == 0 {
+			return false, err
+		}
+		bMD5, err := b.md5()
+		if err != nil {
+			return false, err
+		}
+		return aMD5 == bMD5, nil
+	}
+
+	for _, pair := range [][2]version{{a, b}, {b, a}} {
+		stored, other := pair[0].stored, pair[1]
+		if len(stored.algorithm) == 0 || other.checksum == nil {
+			continue
+		}
+		sum, err := other.checksum(stored)
+		if err != nil || len(sum) == 0 {
+			return false, err
+		}
+		return sum == stored.value, nil
+	}
+	return false, nil
+}
+
+// version of local file at path
+func fileVersion(path string, info fs.FileInfo) version {
+	return version{
+		size:    info.Size(),
+		modTime: info.ModTime(),
+		local:   true,
+		md5: func() (string, error) {
+			f, err := os.Open(path)
+			if err != nil {
+				return "", err
+			}
+			defer f.Close()
+
+			h := md5.New()
+			if _, err := io.Copy(h, f); err != nil {
+				return "", err
+			}
+			return hex.EncodeToString(h.Sum(nil)), nil
+		},
+		checksum: func(s storedChecksum) (string, error) {
+			return fileChecksum(path, s)
+		},
+	}
+}
+
+var md5ETagPattern = regexp.MustCompile(`^"?([0-9a-f]{32})"?$`)
+
+// version of an object from its response headers. Size and modification time of client side encrypted
+// and preserved uploads are the ones of the original file.
+func objectVersion(size int64, lastModified *time.Time, etag string, metadata map[string]string, sse types.ServerSideEncryption, customerKeyMD5 *string) version {
+	v := version{size: size, modTime: aws.ToTime(lastModified)}
+	if t, err := time.Parse(time.RFC3339Nano, metadata[preserveMetaMtime]); err == nil {
+		v.modTime = t
+	}
+
+	// ETags are md5 of contents unless objects are uploaded in parts or encrypted with KMS or customer keys
+	digest := ""
+	if m := md5ETagPattern.FindStringSubmatch(etag); m != nil && !strings.HasPrefix(string(sse), "aws:kms") && customerKeyMD5 == nil {
+		digest = m[1]
+	}
+	if isClientEncrypted(metadata) {
+		v.size, _ = strconv.ParseInt(metadata[cseMetaSize], 10, 64)
+		digest = ""
+	}
+	if len(digest) != 0 {
+		v.md5 = func() (string, error) { return digest, nil }
+	}
+	return v
+}
+
+// version of object at bucket/key headed with headForCheck. Checksums of client side encrypted objects are of
+// their encrypted contents, so they are not compared.
+func (c *Client) headVersion(ctx context.Context, bucket, key string, output *s3.HeadObjectOutput) version {
+	v := objectVersion(aws.ToInt64(output.ContentLength), output.LastModified, aws.ToString(output.ETag), output.Metadata,
+		output.ServerSideEncryption, output.SSECustomerKeyMD5)
+	algorithm, value := c.objectChecksum(output.ChecksumCRC32, output.ChecksumCRC32C, output.ChecksumSHA1, output.ChecksumSHA256)
+	if len(algorithm) == 0 || isClientEncrypted(output.Metadata) {
+		return v
+	}
+
+	v.stored = storedChecksum{algorithm: algorithm, value: value}
+	if _, parts, ok := strings.Cut(value, "-"); ok {
+		v.stored.partSizes = func() ([]int64, error) {
+			n, err := strconv.Atoi(parts)
+			if err != nil {
+				return nil, fmt.Errorf("invalid composite checksum %q", value)
+			}
+			return c.partSizes(ctx, bucket, key, n, isCustomerEncrypted(output.SSECustomerAlgorithm))
+		}
+	}
+	// checksums of objects stored with the same algorithm are compared as they are
+	v.checksum = func(s storedChecksum) (string, error) {
+		if s.algorithm != algorithm {
+			return "", nil
+		}
+		return value, nil
+	}
+	return v
+}
+
+// head object checked by overwrite policies, with its stored checksums when contents are compared
+func (c *Client) headForCheck(ctx context.Context, bucket, key string) (*s3.HeadObjectOutput, error) {
+	return c.head(ctx, bucket, key, c.opts.Overwrite == OverwriteIfDifferent)
+}
+
+// whether existing destinations need to be looked at before being written
+func (c *Client) checksDestination() bool {
+	return c.opts.Overwrite != OverwriteAlways || len(c.opts.BackupSuffix) != 0
+}
+
+// check existing file at path against overwrite policy before it is replaced by object at bucket/key, which is
+// only headed when the policy needs it. Returns path the file is moved to before being replaced, empty when it
+// is not backed up.
+func (c *Client) prepareDownload(ctx context.Context, bucket, key, path string) (string, error) {
+	if !c.checksDestination() {
+		return "", nil
+	}
+
+	info, err := os.Stat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if c.opts.Overwrite == OverwriteNever {
+		return "", &SkippedError{Err: ErrDestinationExists}
+	}
+	if c.opts.Overwrite != OverwriteAlways {
+		output, err := c.headForCheck(ctx, bucket, key)
+		if err != nil {
+			return "", err
+		}
+		if err := c.opts.Overwrite.check(c.headVersion(ctx, bucket, key, output), fileVersion(path, info)); err != nil {
+			return "", err
+		}
+	}
+	if len(c.opts.BackupSuffix) == 0 {
+		return "", nil
+	}
+	return path + c.opts.BackupSuffix, nil
+}
+
+// writeCondition makes writes of objects conditional, so that concurrent writers do not overwrite objects
+// changed after they were checked
+type writeCondition struct {
+	// write only when the key does not exist
+	ifNoneMatch bool
+	// write only when the object has this ETag
+	ifMatch string
+}
+
+// request options sending the condition as headers, SDK inputs have no fields for them
+func (w writeCondition) options() []func(*s3.Options) {
+	header, value := "If-Match", w.ifMatch
+	if w.ifNoneMatch {
+		header, value = "If-None-Match", "*"
+	} else if len(w.ifMatch) == 0 {
+		return nil
+	}
+
+	return []func(*s3.Options){func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, smithyhttp.SetHeaderValue(header, value))
+	}}
+}
+
+// turn rejection of a conditional write into skipping the object
+func (w writeCondition) check(err error) error {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "PreconditionFailed" {
+		return err
+	}
+	if w.ifNoneMatch {
+		return &SkippedError{Err: ErrDestinationExists}
+	}
+	return &SkippedError{Err: ErrDestinationChanged}
+}
+
+// check existing object at bucket/key against overwrite policy before it is written from src, backing it up
+// when configured. Returns condition the object should be written with, conditional is false for writes
+// that cannot take conditions.
+func (c *Client) prepareWrite(ctx context.Context, bucket, key string, conditional bool, src func() (version, error)) (writeCondition, error) {
+	if !c.checksDestination() {
+		return writeCondition{}, nil
+	}
+	if c.opts.Overwrite == OverwriteNever && conditional {
+		// S3 rejects the write when the key exists, no need to look at it
+		return writeCondition{ifNoneMatch: true}, nil
+	}
+
+	output, err := c.headForCheck(ctx, bucket, key)
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return writeCondition{ifNoneMatch: true}, nil
+	}
+	if err != nil {
+		return writeCondition{}, err
+	}
+
+	if c.opts.Overwrite != OverwriteAlways {
+		v, err := src()
+		if err != nil {
+			return writeCondition{}, err
+		}
+		if err := c.opts.Overwrite.check(v, c.headVersion(ctx, bucket, key, output)); err != nil {
+			return writeCondition{}, err
+		}
+	}
+
+	if len(c.opts.BackupSuffix) != 0 {
+		if err := c.backupObject(ctx, bucket, key, output); err != nil {
+			return writeCondition{}, err
+		}
+	}
+	return writeCondition{ifMatch: aws.ToString(output.ETag)}, nil
+}
+
+// copy headed object at bucket/key to key with backup suffix, objects too large for CopyObject are copied in
+// parts keeping their attributes and tags as CopyObject does
+func (c *Client) backupObject(ctx context.Context, bucket, key string, output *s3.HeadObjectOutput) error {
+	etag, size := aws.ToString(output.ETag), aws.ToInt64(output.ContentLength)
+	if size > maxCopyObjectSize {
+		tags, err := c.getTags(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
+		attrs := headAttributes(output)
+		attrs.tagging = encodeTagging(tags)
+
+		input := &s3.CreateMultipartUploadInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key + c.opts.BackupSuffix),
+		}
+		attrs.applyToCreateMultipart(input)
+		c.opts.Encryption.applyToCreateMultipart(input)
+		return c.multipartCopy(ctx, input, copySource(bucket, key), output, writeCondition{}, noProgress{})
+	}
+
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	input := &s3.CopyObjectInput{
+		Bucket:            aws.String(bucket),
+		Key:               aws.String(key + c.opts.BackupSuffix),
+		CopySource:        aws.String(copySource(bucket, key)),
+		CopySourceIfMatch: aws.String(etag),
+	}
+	c.opts.Encryption.applyToCopy(input, isCustomerEncrypted(output.SSECustomerAlgorithm))
+	_, err := c.api.CopyObject(ctx, input)
+	return err
+}
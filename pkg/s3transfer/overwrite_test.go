@@ -0,0 +1,311 @@
+package s3transfer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOverwritePolicyCheck(t *testing.T) {
+	old := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	md5Of := func(s string) func() (string, error) {
+		return func() (string, error) { return s, nil }
+	}
+	stored := storedChecksum{algorithm: types.ChecksumAlgorithmCrc32, value: "AAAAAA=="}
+	checksumOf := func(s string) func(storedChecksum) (string, error) {
+		return func(storedChecksum) (string, error) { return s, nil }
+	}
+
+	cases := []struct {
+		name   string
+		policy OverwritePolicy
+		src    version
+		dest   version
+		want   error
+	}{
+		{"always", OverwriteAlways, version{modTime: old}, version{modTime: old.Add(time.Hour)}, nil},
+		{"never", OverwriteNever, version{modTime: old.Add(time.Hour)}, version{modTime: old}, ErrDestinationExists},
+		{"newer", OverwriteIfNewer, version{modTime: old.Add(time.Hour)}, version{modTime: old}, nil},
+		{"not newer", OverwriteIfNewer, version{modTime: old}, version{modTime: old}, ErrDestinationUpToDate},
+		{"different size", OverwriteIfDifferent, version{size: 1, md5: md5Of("a")}, version{size: 2, md5: md5Of("a")}, nil},
+		{"different contents", OverwriteIfDifferent, version{size: 1, md5: md5Of("a")}, version{size: 1, md5: md5Of("b")}, nil},
+		{"unknown contents", OverwriteIfDifferent, version{size: 1, md5: md5Of("a")}, version{size: 1, md5: md5Of("")}, nil},
+		{"same", OverwriteIfDifferent, version{size: 1, md5: md5Of("a")}, version{size: 1, md5: md5Of("a")}, ErrDestinationUpToDate},
+		{"same checksum", OverwriteIfDifferent, version{size: 1, checksum: checksumOf("AAAAAA==")}, version{size: 1, stored: stored}, ErrDestinationUpToDate},
+		{"same checksum of source", OverwriteIfDifferent, version{size: 1, stored: stored}, version{size: 1, md5: md5Of("a"), checksum: checksumOf("AAAAAA==")}, ErrDestinationUpToDate},
+		{"different checksum", OverwriteIfDifferent, version{size: 1, checksum: checksumOf("AQAAAA==")}, version{size: 1, stored: stored}, nil},
+		{"unknown checksum", OverwriteIfDifferent, version{size: 1, checksum: checksumOf("")}, version{size: 1, stored: stored}, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := c.policy.check(c.src, c.dest)
+			if !errors.Is(err, c.want) || (c.want == nil && err != nil) {
+				t.Errorf("got %v want %v", err, c.want)
+			}
+		})
+	}
+}
+
+func TestSameContentsReadsFileOnce(t *testing.T) {
+	stored := storedChecksum{algorithm: types.ChecksumAlgorithmCrc32, value: "AAAAAA=="}
+	cases := []struct {
+		name     string
+		object   version
+		wantMD5  int
+		wantSum  int
+		wantSame bool
+	}{
+		{"md5 etag", version{md5: func() (string, error) { return "a", nil }, stored: stored}, 1, 0, true},
+		{"stored checksum", version{stored: stored}, 0, 1, true},
+		{"unknown", version{}, 0, 0, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var md5Reads, sumReads int
+			file := version{
+				local: true,
+				md5: func() (string, error) {
+					md5Reads++
+					return "a", nil
+				},
+				checksum: func(storedChecksum) (string, error) {
+					sumReads++
+					return "AAAAAA==", nil
+				},
+			}
+
+			for _, pair := range [][2]version{{file, c.object}, {c.object, file}} {
+				md5Reads, sumReads = 0, 0
+				same, err := sameContents(pair[0], pair[1])
+				require.NoError(t, err)
+				require.Equal(t, c.wantSame, same)
+				if md5Reads != c.wantMD5 || sumReads != c.wantSum {
+					t.Errorf("got %d md5 and %d checksum reads want %d and %d", md5Reads, sumReads, c.wantMD5, c.wantSum)
+				}
+			}
+		})
+	}
+}
+
+func TestObjectVersionMD5(t *testing.T) {
+	cases := []struct {
+		name string
+		etag string
+		want string
+	}{
+		{"single part", `"d41d8cd98f00b204e9800998ecf8427e"`, "d41d8cd98f00b204e9800998ecf8427e"},
+		{"multipart", `"d41d8cd98f00b204e9800998ecf8427e-2"`, ""},
+		{"empty", "", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got string
+			if md5 := objectVersion(0, nil, c.etag, nil, "", nil).md5; md5 != nil {
+				var err error
+				got, err = md5()
+				require.NoError(t, err)
+			}
+			if got != c.want {
+				t.Errorf("got %v want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestCopyOverwriteDownload(t *testing.T) {
+	cases := []struct {
+		name         string
+		policy       OverwritePolicy
+		backupSuffix string
+		local        string
+		localTime    time.Time
+		want         string
+		wantSkipped  int64
+	}{
+		{"never", OverwriteNever, "", "old", time.Now(), "old", 1},
+		{"newer object", OverwriteIfNewer, "", "old", time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), "new", 0},
+		{"newer file", OverwriteIfNewer, "", "old", time.Now(), "old", 1},
+		{"same contents", OverwriteIfDifferent, "", "new", time.Now(), "new", 1},
+		{"different contents", OverwriteIfDifferent, "", "old", time.Now(), "new", 0},
+		{"backup", OverwriteAlways, ".bak", "old", time.Now(), "new", 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, fake, _ := newFakeClient(Options{Overwrite: c.policy, BackupSuffix: c.backupSuffix}, "bucket")
+			fake.Put("bucket", "a", []byte("new"))
+			dest := filepath.Join(t.TempDir(), "a")
+			require.NoError(t, os.WriteFile(dest, []byte(c.local), 0644))
+			require.NoError(t, os.Chtimes(dest, c.localTime, c.localTime))
+
+			result, err := client.Copy(context.Background(), "s3://bucket/a", dest)
+			require.NoError(t, err)
+			require.Equal(t, c.wantSkipped, result.Skipped)
+			// kept files are decided on before the object is requested
+			require.Equal(t, int(1-c.wantSkipped), fake.Calls("GetObject"))
+
+			data, err := os.ReadFile(dest)
+			require.NoError(t, err)
+			require.Equal(t, c.want, string(data))
+
+			if len(c.backupSuffix) != 0 {
+				data, err := os.ReadFile(dest + c.backupSuffix)
+				require.NoError(t, err)
+				require.Equal(t, c.local, string(data))
+			}
+		})
+	}
+}
+
+func TestCopyOverwriteByChecksum(t *testing.T) {
+	cases := []struct {
+		name        string
+		local       string
+		wantSkipped int64
+	}{
+		{"same contents", "0123456789", 1},
+		{"different contents", "0123456780", 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// multipart uploads have no md5 ETags, their composite checksums are compared instead
+			client, fake, _ := newFakeClient(Options{Overwrite: OverwriteIfDifferent, Checksum: types.ChecksumAlgorithmCrc32c}, "bucket")
+			err := client.uploadStream(context.Background(), "bucket", "a", strings.NewReader("0123456789"), 4, ObjectAttributes{}, writeCondition{})
+			require.NoError(t, err)
+			src := filepath.Join(t.TempDir(), "a")
+			require.NoError(t, os.WriteFile(src, []byte(c.local), 0644))
+
+			result, err := client.Copy(context.Background(), src, "s3://bucket/a")
+			require.NoError(t, err)
+			require.Equal(t, c.wantSkipped, result.Skipped)
+
+			result, err = client.Copy(context.Background(), "s3://bucket/a", src)
+			require.NoError(t, err)
+			require.Equal(t, int64(1), result.Skipped)
+			data, _ := fake.Get("bucket", "a")
+			require.Equal(t, c.local, string(data))
+		})
+	}
+}
+
+func TestCopyOverwriteUpload(t *testing.T) {
+	cases := []struct {
+		name         string
+		policy       OverwritePolicy
+		backupSuffix string
+		local        string
+		want         string
+		wantSkipped  int64
+	}{
+		{"always", OverwriteAlways, "", "new", "new", 0},
+		{"never", OverwriteNever, "", "new", "old", 1},
+		{"newer file", OverwriteIfNewer, "", "new", "new", 0},
+		{"same contents", OverwriteIfDifferent, "", "old", "old", 1},
+		{"different contents", OverwriteIfDifferent, "", "new", "new", 0},
+		{"backup", OverwriteAlways, ".bak", "new", "new", 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, fake, _ := newFakeClient(Options{Overwrite: c.policy, BackupSuffix: c.backupSuffix}, "bucket")
+			fake.Put("bucket", "a", []byte("old"))
+			src := filepath.Join(t.TempDir(), "a")
+			require.NoError(t, os.WriteFile(src, []byte(c.local), 0644))
+
+			result, err := client.Copy(context.Background(), src, "s3://bucket/a")
+			require.NoError(t, err)
+			require.Equal(t, c.wantSkipped, result.Skipped)
+
+			data, _ := fake.Get("bucket", "a")
+			require.Equal(t, c.want, string(data))
+
+			if len(c.backupSuffix) != 0 {
+				data, _ := fake.Get("bucket", "a"+c.backupSuffix)
+				require.Equal(t, "old", string(data))
+			}
+		})
+	}
+}
+
+func TestCopyOverwriteNeverServerSide(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{Overwrite: OverwriteNever}, "bucket")
+	fake.Put("bucket", "a", []byte("new"))
+	fake.Put("bucket", "b", []byte("old"))
+
+	result, err := client.Copy(context.Background(), "s3://bucket/a", "s3://bucket/b")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.Skipped)
+	data, _ := fake.Get("bucket", "b")
+	require.Equal(t, "old", string(data))
+}
+
+func TestCopyOverwriteLargeBackup(t *testing.T) {
+	defer func(size, partSize int64) { maxCopyObjectSize, copyPartSize = size, partSize }(maxCopyObjectSize, copyPartSize)
+	maxCopyObjectSize, copyPartSize = 2, 2
+
+	client, fake, _ := newFakeClient(Options{BackupSuffix: ".bak"}, "bucket")
+	_, err := fake.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:   aws.String("bucket"),
+		Key:      aws.String("a"),
+		Body:     strings.NewReader("old"),
+		Metadata: map[string]string{"team": "web"},
+		Tagging:  aws.String("env=prod"),
+	})
+	require.NoError(t, err)
+	src := filepath.Join(t.TempDir(), "a")
+	require.NoError(t, os.WriteFile(src, []byte("new"), 0644))
+
+	_, err = client.Copy(context.Background(), src, "s3://bucket/a")
+	require.NoError(t, err)
+
+	data, _ := fake.Get("bucket", "a.bak")
+	require.Equal(t, "old", string(data))
+	require.Equal(t, 2, fake.Calls("UploadPartCopy"))
+	require.Equal(t, map[string]string{"team": "web"}, fake.Metadata("bucket", "a.bak"))
+	require.Equal(t, map[string]string{"env": "prod"}, fake.Tags("bucket", "a.bak"))
+	data, _ = fake.Get("bucket", "a")
+	require.Equal(t, "new", string(data))
+}
+
+func TestConditionalWriteRejected(t *testing.T) {
+	cases := []struct {
+		name string
+		cond writeCondition
+		body string
+		want error
+	}{
+		{"exists", writeCondition{ifNoneMatch: true}, "01", ErrDestinationExists},
+		{"exists multipart", writeCondition{ifNoneMatch: true}, "0123456789", ErrDestinationExists},
+		{"changed", writeCondition{ifMatch: `"etag"`}, "01", ErrDestinationChanged},
+		{"changed multipart", writeCondition{ifMatch: `"etag"`}, "0123456789", ErrDestinationChanged},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, fake, _ := newFakeClient(Options{}, "bucket")
+			fake.Put("bucket", "key", []byte("old"))
+
+			err := client.uploadStream(context.Background(), "bucket", "key", strings.NewReader(c.body), 4, ObjectAttributes{}, c.cond)
+			var skipped *SkippedError
+			require.ErrorAs(t, err, &skipped)
+			require.ErrorIs(t, err, c.want)
+
+			data, _ := fake.Get("bucket", "key")
+			require.Equal(t, "old", string(data))
+			require.Equal(t, 0, fake.PendingUploads())
+		})
+	}
+}
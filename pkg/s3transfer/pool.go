@@ -0,0 +1,163 @@
+package s3transfer
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// number of functions run by the pool and skipped because of cancellation
+type poolCounters struct {
+	completed atomic.Int64
+	skipped   atomic.Int64
+}
+
+// PoolStats is a snapshot of the worker pools of a client
+type PoolStats struct {
+	// workers running a function
+	InFlight int64
+	// functions queued for the workers
+	Queued int64
+}
+
+// poolRegistry tracks running pools for PoolStats
+type poolRegistry struct {
+	inFlight atomic.Int64
+	mu       sync.Mutex
+	queues   map[<-chan func() error]struct{}
+}
+
+func (r *poolRegistry) add(fnch <-chan func() error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.queues == nil {
+		r.queues = make(map[<-chan func() error]struct{})
+	}
+	r.queues[fnch] = struct{}{}
+}
+
+func (r *poolRegistry) remove(fnch <-chan func() error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.queues, fnch)
+}
+
+// PoolStats returns the number of busy workers and queued functions of running transfers, safe to call
+// from any goroutine, i.e. for exposing metrics
+func (c *Client) PoolStats() PoolStats {
+	c.pools.mu.Lock()
+	defer c.pools.mu.Unlock()
+
+	stats := PoolStats{InFlight: c.pools.inFlight.Load()}
+	for fnch := range c.pools.queues {
+		stats.Queued += int64(len(fnch))
+	}
+	return stats
+}
+
+func (c *Client) runWithErrgroup(ctx context.Context, fnch <-chan func() error, counters *poolCounters) error {
+	c.pools.add(fnch)
+	defer c.pools.remove(fnch)
+
+	errg := new(errgroup.Group)
+	for i := 0; i < c.opts.Concurrency; i++ {
+		errg.Go(func() error {
+			for {
+				fn, open := <-fnch
+				if !open {
+					break
+				}
+
+				// drain queued functions without running them once cancelled, in-flight ones are left to finish
+				// when they run under inFlightContext
+				if ctx.Err() != nil {
+					counters.skipped.Add(1)
+					continue
+				}
+
+				c.pools.inFlight.Add(1)
+				err := fn()
+				c.pools.inFlight.Add(-1)
+				if err != nil {
+					return err
+				}
+				counters.completed.Add(1)
+			}
+			return nil
+		})
+	}
+
+	if err := errg.Wait(); err != nil {
+		return err
+	}
+	return nil
+}
+
+type abortKey struct{}
+
+// WithAbort returns a copy of ctx under which cancelling ctx only stops transfers from starting. Transfers in
+// flight keep running until abort is done or the deadline of ctx passes.
+func WithAbort(ctx, abort context.Context) context.Context {
+	return context.WithValue(ctx, abortKey{}, abort)
+}
+
+// context transfers started under ctx run with, ctx itself unless it was given an abort context by WithAbort
+func inFlightContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	abort, ok := ctx.Value(abortKey{}).(context.Context)
+	if !ok {
+		return ctx, func() {}
+	}
+
+	work, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	if abort.Err() != nil {
+		cancel()
+	}
+	stop := context.AfterFunc(abort, cancel)
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancelDeadline context.CancelFunc
+		work, cancelDeadline = context.WithDeadline(work, deadline)
+		return work, func() { stop(); cancelDeadline(); cancel() }
+	}
+	return work, func() { stop(); cancel() }
+}
+
+// pooledRun is a pool of workers started by runPooled
+type pooledRun struct {
+	wg      sync.WaitGroup
+	err     error
+	skipped int64
+}
+
+// run functions read from fnch in a pool of goroutines and report events written to evch, exit on error
+func (c *Client) runPooled(ctx context.Context, cancel context.CancelFunc, fnch <-chan func() error, evch chan Event, result *Result) *pooledRun {
+	run := &pooledRun{}
+	run.wg.Add(2)
+	go func() {
+		defer run.wg.Done()
+		var counters poolCounters
+		run.err = c.runWithErrgroup(ctx, fnch, &counters)
+		if run.err != nil {
+			cancel()
+		}
+		run.skipped = counters.skipped.Load()
+		close(evch)
+	}()
+
+	go func() {
+		defer run.wg.Done()
+		for e := range evch {
+			c.report(result, e)
+		}
+	}()
+
+	return run
+}
+
+// wait until all functions are run and their events are reported, fnch should be closed before
+func (r *pooledRun) wait(result *Result) error {
+	r.wg.Wait()
+	result.Skipped += r.skipped
+	return r.err
+}
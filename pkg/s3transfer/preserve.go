@@ -0,0 +1,62 @@
+package s3transfer
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strconv"
+	"time"
+)
+
+// user metadata keys of file attributes preserved by uploads
+const (
+	preserveMetaMtime = "s3cli-mtime"
+	preserveMetaMode  = "s3cli-mode"
+	preserveMetaUID   = "s3cli-uid"
+	preserveMetaGID   = "s3cli-gid"
+)
+
+// metadata recording modification time, permissions and owner of a file
+func fileAttributesMetadata(info fs.FileInfo) map[string]string {
+	metadata := map[string]string{
+		preserveMetaMtime: info.ModTime().UTC().Format(time.RFC3339Nano),
+		preserveMetaMode:  strconv.FormatUint(uint64(info.Mode().Perm()), 8),
+	}
+	if uid, gid, ok := fileOwner(info); ok {
+		metadata[preserveMetaUID] = strconv.Itoa(uid)
+		metadata[preserveMetaGID] = strconv.Itoa(gid)
+	}
+	return metadata
+}
+
+// restore attributes recorded in metadata on downloaded file at path, modification time is set to lastModified
+// of the object when not recorded. Owner is only restored when permitted, which usually requires root.
+func restoreFileAttributes(path string, metadata map[string]string, lastModified *time.Time) error {
+	mtime, err := time.Parse(time.RFC3339Nano, metadata[preserveMetaMtime])
+	if err != nil {
+		if lastModified == nil {
+			return nil
+		}
+		mtime = *lastModified
+	}
+
+	if s, ok := metadata[preserveMetaMode]; ok {
+		mode, err := strconv.ParseUint(s, 8, 32)
+		if err != nil {
+			return errors.New("invalid preserved file mode " + s)
+		}
+		if err := os.Chmod(path, fs.FileMode(mode).Perm()); err != nil {
+			return err
+		}
+	}
+
+	uid, uerr := strconv.Atoi(metadata[preserveMetaUID])
+	gid, gerr := strconv.Atoi(metadata[preserveMetaGID])
+	if uerr == nil && gerr == nil {
+		if err := setFileOwner(path, uid, gid); err != nil && !errors.Is(err, fs.ErrPermission) {
+			return err
+		}
+	}
+
+	return os.Chtimes(path, mtime, mtime)
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package s3transfer
+
+import "io/fs"
+
+// owners are not preserved on platforms without unix ownership
+
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	return 0, 0, false
+}
+
+func setFileOwner(path string, uid, gid int) error {
+	return nil
+}
@@ -0,0 +1,70 @@
+package s3transfer
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopyPreserve(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{Preserve: true}, "bucket")
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{"a": "a"})
+	mtime := time.Date(2020, 2, 3, 4, 5, 6, 789, time.UTC)
+	require.NoError(t, os.Chmod(filepath.Join(src, "a"), 0640))
+	require.NoError(t, os.Chtimes(filepath.Join(src, "a"), mtime, mtime))
+
+	_, err := client.Copy(context.Background(), filepath.Join(src, "a"), "s3://bucket/a")
+	require.NoError(t, err)
+	metadata := fake.Metadata("bucket", "a")
+	require.Equal(t, "2020-02-03T04:05:06.000000789Z", metadata[preserveMetaMtime])
+
+	dest := t.TempDir()
+	_, err = client.Copy(context.Background(), "s3://bucket/a", filepath.Join(dest, "a"))
+	require.NoError(t, err)
+
+	info, err := os.Stat(filepath.Join(dest, "a"))
+	require.NoError(t, err)
+	require.True(t, mtime.Equal(info.ModTime()), info.ModTime())
+	if runtime.GOOS != "windows" {
+		require.Equal(t, "640", metadata[preserveMetaMode])
+		require.Equal(t, fs.FileMode(0640), info.Mode().Perm())
+	}
+}
+
+func TestCopyPreserveWithoutMetadata(t *testing.T) {
+	cases := []struct {
+		name     string
+		preserve bool
+	}{
+		{"preserve", true},
+		{"no preserve", false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, fake, _ := newFakeClient(Options{Preserve: c.preserve}, "bucket")
+			fake.Put("bucket", "a", []byte("a"))
+			output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("a")})
+			require.NoError(t, err)
+
+			dest := t.TempDir()
+			_, err = client.Copy(context.Background(), "s3://bucket/a", filepath.Join(dest, "a"))
+			require.NoError(t, err)
+
+			info, err := os.Stat(filepath.Join(dest, "a"))
+			require.NoError(t, err)
+			if got := info.ModTime().Equal(aws.ToTime(output.LastModified)); got != c.preserve {
+				t.Errorf("got %v want %v", got, c.preserve)
+			}
+		})
+	}
+}
@@ -0,0 +1,21 @@
+//go:build unix
+
+package s3transfer
+
+import (
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+func fileOwner(info fs.FileInfo) (uid, gid int, ok bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0, false
+	}
+	return int(st.Uid), int(st.Gid), true
+}
+
+func setFileOwner(path string, uid, gid int) error {
+	return os.Lchown(path, uid, gid)
+}
@@ -0,0 +1,91 @@
+package s3transfer
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ReadRange returns up to length bytes of object at bucket/key starting at offset, negative length reads until
+// the end of the object. Client side encrypted objects are decrypted, downloading only the segments holding
+// the range. The request timeout covers reading the range, which ends when the reader is closed.
+func (c *Client) ReadRange(ctx context.Context, bucket, key string, offset, length int64) (io.ReadCloser, error) {
+	if offset < 0 {
+		return nil, fmt.Errorf("negative offset %d", offset)
+	}
+
+	output, err := c.Head(ctx, bucket, key)
+	if err != nil {
+		return nil, err
+	}
+
+	size := aws.ToInt64(output.ContentLength)
+	var obj *cseObject
+	if isClientEncrypted(output.Metadata) {
+		if obj, err = c.opts.ClientEncryption.openObject(output.Metadata); err != nil {
+			return nil, err
+		}
+		size = obj.size
+	}
+
+	end := size - 1
+	if length >= 0 {
+		end = min(end, offset+length-1)
+	}
+	if offset > end {
+		return io.NopCloser(bytes.NewReader(nil)), nil
+	}
+
+	getStart, getEnd, segment := offset, end, int64(0)
+	if obj != nil {
+		getStart, getEnd, segment = obj.encryptedRange(offset, end)
+	}
+
+	ctx, cancel := c.requestContext(ctx)
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", getStart, getEnd)),
+		// the object read is the one looked at
+		IfMatch: output.ETag,
+	}
+	c.opts.Encryption.applyToGet(input)
+	got, err := c.api.GetObject(ctx, input)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	r := &rangeReader{body: got.Body, cancel: cancel}
+	r.r = c.bandwidth.wrapDownload(ctx, got.Body)
+	if obj != nil {
+		dr := newDecryptingReader(obj, r.r, segment)
+		// the range starts inside its first segment
+		if _, err := io.CopyN(io.Discard, dr, offset-segment*cseSegmentSize); err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.r = io.LimitReader(dr, end-offset+1)
+	}
+	return r, nil
+}
+
+// rangeReader is the body of a ranged read, closing it ends the request
+type rangeReader struct {
+	r      io.Reader
+	body   io.Closer
+	cancel context.CancelFunc
+}
+
+func (r *rangeReader) Read(p []byte) (int, error) {
+	return r.r.Read(p)
+}
+
+func (r *rangeReader) Close() error {
+	defer r.cancel()
+	return r.body.Close()
+}
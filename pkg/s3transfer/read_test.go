@@ -0,0 +1,53 @@
+package s3transfer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadRange(t *testing.T) {
+	ce, err := NewClientEncryption(bytes.Repeat([]byte{1}, 32))
+	require.NoError(t, err)
+	ce.EncryptUploads = true
+	client, fake, _ := newFakeClient(Options{ClientEncryption: ce}, "bucket")
+
+	plain := randomData(t, 3*cseSegmentSize+100)
+	src := filepath.Join(t.TempDir(), "a")
+	require.NoError(t, os.WriteFile(src, plain, 0644))
+	_, err = client.Copy(context.Background(), src, "s3://bucket/encrypted")
+	require.NoError(t, err)
+	fake.Put("bucket", "plain", plain)
+
+	size := int64(len(plain))
+	cases := []struct {
+		name           string
+		offset, length int64
+		want           []byte
+	}{
+		{"within a segment", 10, 20, plain[10:30]},
+		{"across segments", cseSegmentSize - 5, cseSegmentSize + 10, plain[cseSegmentSize-5 : 2*cseSegmentSize+5]},
+		{"last segment", 3 * cseSegmentSize, -1, plain[3*cseSegmentSize:]},
+		{"past the end", size - 10, 100, plain[size-10:]},
+		{"empty", size, 10, []byte{}},
+	}
+
+	for _, key := range []string{"encrypted", "plain"} {
+		for _, c := range cases {
+			t.Run(key+" "+c.name, func(t *testing.T) {
+				r, err := client.ReadRange(context.Background(), "bucket", key, c.offset, c.length)
+				require.NoError(t, err)
+				defer r.Close()
+
+				got, err := io.ReadAll(r)
+				require.NoError(t, err)
+				require.Equal(t, c.want, got)
+			})
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package s3transfer
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Remove deletes objects at s3 paths, paths ending with * delete all objects under the prefix
+func (c *Client) Remove(ctx context.Context, paths []string) (Result, error) {
+	var result Result
+	globs, regulars := splitGlobsAndRegulars(paths)
+	err := c.removePaths(ctx, regulars, &result)
+	if err != nil {
+		return result, err
+	}
+
+	err = c.removeGlobs(ctx, globs, &result)
+	return result, err
+}
+
+func splitGlobsAndRegulars(paths []string) (globs, regulars []string) {
+	for _, p := range paths {
+		if strings.HasSuffix(p, "*") {
+			globs = append(globs, p)
+			continue
+		}
+		regulars = append(regulars, p)
+	}
+	return
+}
+
+// group paths by bucket name i.e b1: [k1,k2,k3], b2: [k4, k5]
+func groupByBucket(paths []string) (map[string][]string, error) {
+	groupByBucket := make(map[string][]string)
+	for _, p := range paths {
+		bucket, key, err := ParsePath(p)
+		if err != nil {
+			return nil, err
+		}
+		groupByBucket[bucket] = append(groupByBucket[bucket], key)
+	}
+	return groupByBucket, nil
+}
+
+func (c *Client) removeGlobs(ctx context.Context, paths []string, result *Result) error {
+	bucketGroups, err := groupByBucket(paths)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fnch := make(chan func() error, c.opts.Concurrency)
+	evch := make(chan Event, c.opts.Concurrency)
+	run := c.runPooled(ctx, cancel, fnch, evch, result)
+	work, cancelWork := inFlightContext(ctx)
+	defer cancelWork()
+
+loop:
+	for b, keys := range bucketGroups {
+		for _, k := range keys {
+			b, k := b, k
+			select {
+			case <-ctx.Done():
+				break loop
+			case fnch <- func() error {
+				return c.removeGlob(work, b, k, evch)
+			}:
+				// noop
+			}
+		}
+	}
+
+	close(fnch)
+	return run.wait(result)
+}
+
+func (c *Client) removeGlob(ctx context.Context, bucket, prefix string, evch chan<- Event) error {
+	prefix = strings.TrimSuffix(prefix, "*")
+
+	var deleteErr error
+	err := c.List(ctx, ListParams{Bucket: bucket, Prefix: prefix}, func(output *s3.ListObjectsV2Output) {
+		if deleteErr != nil || len(output.Contents) == 0 {
+			return
+		}
+
+		keys := make([]string, 0, len(output.Contents))
+		for _, o := range output.Contents {
+			keys = append(keys, aws.ToString(o.Key))
+		}
+
+		start := time.Now()
+		deleteOutput, err := c.removeObjects(ctx, bucket, keys)
+		for _, e := range deleteEvents(bucket, keys, deleteOutput, start, err) {
+			evch <- e
+		}
+		if err != nil {
+			deleteErr = err
+		}
+	})
+
+	if err != nil {
+		return err
+	}
+	return deleteErr
+}
+
+func (c *Client) removePaths(ctx context.Context, paths []string, result *Result) error {
+	bucketGroups, err := groupByBucket(paths)
+	if err != nil {
+		return err
+	}
+
+	work, cancel := inFlightContext(ctx)
+	defer cancel()
+
+	for b, keys := range bucketGroups {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		start := time.Now()
+		output, err := c.removeObjects(work, b, keys)
+		for _, e := range deleteEvents(b, keys, output, start, err) {
+			c.report(result, e)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// events for deleted objects and objects that could not be deleted, all keys failed when the request failed
+func deleteEvents(bucket string, keys []string, output *s3.DeleteObjectsOutput, start time.Time, err error) []Event {
+	if err != nil {
+		events := make([]Event, 0, len(keys))
+		for _, k := range keys {
+			events = append(events, newEvent(OpDelete, S3Path(bucket, k), "", 0, start, err))
+		}
+		return events
+	}
+
+	events := make([]Event, 0, len(output.Deleted)+len(output.Errors))
+	for _, d := range output.Deleted {
+		events = append(events, newEvent(OpDelete, S3Path(bucket, aws.ToString(d.Key)), "", 0, start, nil))
+	}
+
+	for _, e := range output.Errors {
+		err := &DeleteError{Code: aws.ToString(e.Code), Message: aws.ToString(e.Message)}
+		events = append(events, newEvent(OpDelete, S3Path(bucket, aws.ToString(e.Key)), "", 0, start, err))
+	}
+	return events
+}
+
+func (c *Client) removeObjects(ctx context.Context, bucket string, keys []string) (*s3.DeleteObjectsOutput, error) {
+	delete := types.Delete{}
+	for _, k := range keys {
+		delete.Objects = append(delete.Objects, types.ObjectIdentifier{Key: aws.String(k)})
+	}
+
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	output, err := c.api.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(bucket),
+		Delete: &delete,
+	})
+	if err != nil {
+		return output, err
+	}
+	return output, nil
+}
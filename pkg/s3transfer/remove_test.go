@@ -1,4 +1,4 @@
-package cmd
+package s3transfer
 
 import (
 	"reflect"
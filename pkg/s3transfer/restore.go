@@ -0,0 +1,387 @@
+package s3transfer
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// ArchivedPolicy selects what downloads and copies do with archived objects that are not restored
+type ArchivedPolicy string
+
+const (
+	// fail the object, as S3 does
+	ArchivedFail ArchivedPolicy = ""
+	// skip the object without failing the run
+	ArchivedSkip ArchivedPolicy = "skip"
+	// request a restore unless one is in progress and wait for it to complete
+	ArchivedWait ArchivedPolicy = "wait"
+)
+
+// default interval between checks of restores being waited for
+const defaultRestorePollInterval = time.Minute
+
+// RestoreParams configures restores of archived objects
+type RestoreParams struct {
+	// number of days restored copies are kept, defaults to 1
+	Days int32
+	// retrieval tier, Standard when empty
+	Tier types.Tier
+}
+
+// RestoreStatus is the restore state of an archived object, read from its x-amz-restore header
+type RestoreStatus struct {
+	Path         string
+	StorageClass types.StorageClass
+	// restore was requested and is not complete yet
+	Ongoing bool
+	// expiry of the restored copy, nil when the object is not restored
+	Expiry *time.Time
+}
+
+// Restored reports whether the object can be read
+func (s RestoreStatus) Restored() bool {
+	return !s.Ongoing && s.Expiry != nil
+}
+
+var restoreHeaderPattern = regexp.MustCompile(`ongoing-request="(true|false)"(?:,\s*expiry-date="([^"]+)")?`)
+
+// parse x-amz-restore header i.e. ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"
+func parseRestoreHeader(header string) (ongoing bool, expiry *time.Time) {
+	m := restoreHeaderPattern.FindStringSubmatch(header)
+	if m == nil {
+		return false, nil
+	}
+
+	if t, err := http.ParseTime(m[2]); err == nil {
+		expiry = &t
+	}
+	return m[1] == "true", expiry
+}
+
+// storage classes objects need to be restored from before being read
+func isArchivedClass(class string) bool {
+	return class == string(types.StorageClassGlacier) || class == string(types.StorageClassDeepArchive)
+}
+
+func isArchivedObject(o types.Object) bool {
+	return isArchivedClass(string(o.StorageClass))
+}
+
+// isArchivedError reports whether err is S3 refusing to read an archived object that is not restored
+func isArchivedError(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "InvalidObjectState"
+}
+
+// Restore requests restores of archived objects at s3 paths, paths ending with * restore all archived
+// objects under the prefix
+func (c *Client) Restore(ctx context.Context, paths []string, params RestoreParams) (Result, error) {
+	var result Result
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fnch := make(chan func() error, c.opts.Concurrency)
+	evch := make(chan Event, c.opts.Concurrency)
+	run := c.runPooled(ctx, cancel, fnch, evch, &result)
+	work, cancelWork := inFlightContext(ctx)
+	defer cancelWork()
+
+	enqueue := func(bucket, key string) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case fnch <- func() error {
+			start := time.Now()
+			err := c.restoreObject(work, bucket, key, params)
+			evch <- newEvent(OpRestore, S3Path(bucket, key), "", 0, start, err)
+			return err
+		}:
+			return true
+		}
+	}
+
+	err := c.forEachObject(ctx, paths, isArchivedObject, enqueue)
+
+	close(fnch)
+	if werr := run.wait(&result); werr != nil {
+		return result, werr
+	}
+	return result, err
+}
+
+// request restore of a single object, a restore already in progress is not an error
+func (c *Client) restoreObject(ctx context.Context, bucket, key string, params RestoreParams) error {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	days := params.Days
+	if days <= 0 {
+		days = 1
+	}
+	request := &types.RestoreRequest{Days: aws.Int32(days)}
+	if len(params.Tier) != 0 {
+		request.GlacierJobParameters = &types.GlacierJobParameters{Tier: params.Tier}
+	}
+
+	_, err := c.api.RestoreObject(ctx, &s3.RestoreObjectInput{
+		Bucket:         aws.String(bucket),
+		Key:            aws.String(key),
+		RestoreRequest: request,
+	})
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "RestoreAlreadyInProgress" {
+		return nil
+	}
+	return err
+}
+
+// RestoreStatus returns restore states of archived objects at s3 paths sorted by path, paths ending with *
+// report all archived objects under the prefix
+func (c *Client) RestoreStatus(ctx context.Context, paths []string) ([]RestoreStatus, error) {
+	var mu sync.Mutex
+	var statuses []RestoreStatus
+	err := c.runForEachObject(ctx, paths, isArchivedObject, func(ctx context.Context, bucket, key string) error {
+		s, err := c.restoreStatus(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		statuses = append(statuses, s)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Path < statuses[j].Path })
+	return statuses, nil
+}
+
+func (c *Client) restoreStatus(ctx context.Context, bucket, key string) (RestoreStatus, error) {
+	output, err := c.Head(ctx, bucket, key)
+	if err != nil {
+		return RestoreStatus{}, err
+	}
+
+	s := RestoreStatus{Path: S3Path(bucket, key), StorageClass: output.StorageClass}
+	s.Ongoing, s.Expiry = parseRestoreHeader(aws.ToString(output.Restore))
+	return s, nil
+}
+
+// handle err of an operation on an object as configured by Options.Archived, returns true when the operation
+// should be run again because the object was restored
+func (c *Client) handleArchived(ctx context.Context, bucket, key string, err error) (bool, error) {
+	if !isArchivedError(err) {
+		return false, err
+	}
+
+	switch c.opts.Archived {
+	case ArchivedSkip:
+		return false, &SkippedError{Err: err}
+	case ArchivedWait:
+		if werr := c.waitForRestore(ctx, bucket, key); werr != nil {
+			return false, werr
+		}
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
+// request restore of an object unless one is in progress and poll until it completes. Holds the worker it
+// runs in, objects found by listing are waited for by restoreWaiter instead.
+func (c *Client) waitForRestore(ctx context.Context, bucket, key string) error {
+	interval := c.restorePollInterval()
+	requested := false
+	for {
+		s, err := c.restoreStatus(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
+		if s.Restored() {
+			return nil
+		}
+
+		if !s.Ongoing && !requested {
+			if err := c.restoreObject(ctx, bucket, key, c.opts.Restore); err != nil {
+				return err
+			}
+			requested = true
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (c *Client) restorePollInterval() time.Duration {
+	if c.opts.RestorePollInterval <= 0 {
+		return defaultRestorePollInterval
+	}
+	return c.opts.RestorePollInterval
+}
+
+// restoreWaiter checks archived objects as they are listed and enqueues their operations once restored, so
+// that objects being restored do not hold workers of the pool while others wait in the queue. Checks and
+// restore requests run in the pool, operations on objects found restored run right away.
+type restoreWaiter struct {
+	c    *Client
+	fnch chan<- func() error
+	// context checks and restore requests run with
+	work context.Context
+	// signalled when a check completes
+	checked chan struct{}
+
+	mu       sync.Mutex
+	checking int
+	pending  []pendingRestore
+}
+
+type pendingRestore struct {
+	bucket, key string
+	fn          func() error
+}
+
+func newRestoreWaiter(c *Client, fnch chan<- func() error, work context.Context) *restoreWaiter {
+	return &restoreWaiter{c: c, fnch: fnch, work: work, checked: make(chan struct{}, 1)}
+}
+
+// enqueue fn, returns false when the run is stopped
+func (w *restoreWaiter) enqueue(ctx context.Context, fn func() error) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case w.fnch <- fn:
+		return true
+	}
+}
+
+// enqueue operation fn on listed object o, or a check of o running fn once it is restored when archived
+// objects are waited for. Returns false when the run is stopped.
+func (w *restoreWaiter) add(ctx context.Context, bucket string, o types.Object, fn func() error) bool {
+	if w.c.opts.Archived != ArchivedWait || !isArchivedObject(o) {
+		return w.enqueue(ctx, fn)
+	}
+
+	key := aws.ToString(o.Key)
+	w.mu.Lock()
+	w.checking++
+	w.mu.Unlock()
+	enqueued := w.enqueue(ctx, func() error {
+		defer w.checkDone()
+		return w.check(bucket, key, fn)
+	})
+	if !enqueued {
+		w.checkDone()
+	}
+	return enqueued
+}
+
+// run fn when the object is restored, otherwise request its restore unless one is in progress and leave fn
+// pending. Operations on objects whose restore cannot be checked or requested run right away and fail as usual.
+func (w *restoreWaiter) check(bucket, key string, fn func() error) error {
+	s, err := w.c.restoreStatus(w.work, bucket, key)
+	if err != nil || s.Restored() {
+		return fn()
+	}
+
+	if !s.Ongoing {
+		if err := w.c.restoreObject(w.work, bucket, key, w.c.opts.Restore); err != nil {
+			return fn()
+		}
+	}
+
+	w.mu.Lock()
+	w.pending = append(w.pending, pendingRestore{bucket: bucket, key: key, fn: fn})
+	w.mu.Unlock()
+	return nil
+}
+
+func (w *restoreWaiter) checkDone() {
+	w.mu.Lock()
+	w.checking--
+	w.mu.Unlock()
+
+	select {
+	case w.checked <- struct{}{}:
+	default:
+	}
+}
+
+// wait for checks of listed objects and poll pending ones, enqueueing their operations as restores complete.
+// Returns when nothing is left or the run is stopped.
+func (w *restoreWaiter) wait(ctx context.Context) {
+	ticker := time.NewTicker(w.c.restorePollInterval())
+	defer ticker.Stop()
+
+	for {
+		w.mu.Lock()
+		idle := w.checking == 0 && len(w.pending) == 0
+		w.mu.Unlock()
+		if idle {
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.checked:
+		case <-ticker.C:
+			if !w.poll(ctx) {
+				return
+			}
+		}
+	}
+}
+
+// enqueue operations of pending objects whose restores completed, returns false when the run is stopped
+func (w *restoreWaiter) poll(ctx context.Context) bool {
+	w.mu.Lock()
+	pending := w.pending
+	w.pending = nil
+	w.mu.Unlock()
+
+	var left []pendingRestore
+	stopped := false
+	for i, p := range pending {
+		s, err := w.c.restoreStatus(ctx, p.bucket, p.key)
+		if err == nil && !s.Restored() {
+			left = append(left, p)
+			continue
+		}
+		// errors are reported by the operation
+		if !w.enqueue(ctx, p.fn) {
+			left = append(left, pending[i:]...)
+			stopped = true
+			break
+		}
+	}
+
+	w.mu.Lock()
+	w.pending = append(w.pending, left...)
+	w.mu.Unlock()
+	return !stopped
+}
+
+// number of objects whose operations were not enqueued, known once the pool has finished
+func (w *restoreWaiter) waiting() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return int64(len(w.pending))
+}
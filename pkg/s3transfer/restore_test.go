@@ -0,0 +1,199 @@
+package s3transfer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/internal/s3fake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRestoreHeader(t *testing.T) {
+	expiry := time.Date(2012, 12, 21, 0, 0, 0, 0, time.UTC)
+	cases := []struct {
+		input       string
+		wantOngoing bool
+		wantExpiry  *time.Time
+	}{
+		{"", false, nil},
+		{`ongoing-request="true"`, true, nil},
+		{`ongoing-request="false", expiry-date="Fri, 21 Dec 2012 00:00:00 GMT"`, false, &expiry},
+	}
+
+	for _, c := range cases {
+		t.Run(c.input, func(t *testing.T) {
+			ongoing, expiry := parseRestoreHeader(c.input)
+			if ongoing != c.wantOngoing {
+				t.Errorf("got %v want %v", ongoing, c.wantOngoing)
+			}
+			require.Equal(t, c.wantExpiry, expiry)
+		})
+	}
+}
+
+// put objects in given storage classes into bucket
+func putArchived(t *testing.T, fake *s3fake.Client, bucket string, classes map[string]types.StorageClass) {
+	for key, class := range classes {
+		_, err := fake.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String(bucket), Key: aws.String(key), StorageClass: class})
+		require.NoError(t, err)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	client, fake, recorder := newFakeClient(Options{}, "bucket")
+	fake.RestoreDuration = time.Hour
+	putArchived(t, fake, "bucket", map[string]types.StorageClass{
+		"logs/a": types.StorageClassGlacier,
+		"logs/b": types.StorageClassDeepArchive,
+		"logs/c": types.StorageClassStandard,
+		"other":  types.StorageClassGlacier,
+	})
+
+	result, err := client.Restore(context.Background(), []string{"s3://bucket/logs/*"}, RestoreParams{Days: 3, Tier: types.TierBulk})
+	require.NoError(t, err)
+	require.Equal(t, Result{Completed: 2}, result)
+	require.ElementsMatch(t, []string{"s3://bucket/logs/a", "s3://bucket/logs/b"}, recorder.sources(OpRestore))
+
+	// restores in progress are not errors
+	result, err = client.Restore(context.Background(), []string{"s3://bucket/logs/a", "s3://bucket/other"}, RestoreParams{})
+	require.NoError(t, err)
+	require.Equal(t, Result{Completed: 2}, result)
+
+	statuses, err := client.RestoreStatus(context.Background(), []string{"s3://bucket/logs/*", "s3://bucket/other"})
+	require.NoError(t, err)
+	require.Len(t, statuses, 3)
+	for _, s := range statuses {
+		require.True(t, s.Ongoing, s.Path)
+	}
+	require.Equal(t, "s3://bucket/logs/a", statuses[0].Path)
+	require.Equal(t, types.StorageClassGlacier, statuses[0].StorageClass)
+}
+
+func TestRestoreNotArchived(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{}, "bucket")
+	putArchived(t, fake, "bucket", map[string]types.StorageClass{"a": types.StorageClassStandard})
+
+	result, err := client.Restore(context.Background(), []string{"s3://bucket/a"}, RestoreParams{})
+	require.Error(t, err)
+	require.Equal(t, Result{Failed: 1}, result)
+}
+
+func TestCopyArchived(t *testing.T) {
+	cases := []struct {
+		name       string
+		policy     ArchivedPolicy
+		wantResult Result
+		wantErr    bool
+		wantFiles  []string
+	}{
+		{"fail", ArchivedFail, Result{Failed: 1}, true, nil},
+		{"skip", ArchivedSkip, Result{Completed: 1, Skipped: 1}, false, []string{"standard"}},
+		{"wait", ArchivedWait, Result{Completed: 2}, false, []string{"archived", "standard"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, fake, _ := newFakeClient(Options{Concurrency: 1, Archived: c.policy, RestorePollInterval: 5 * time.Millisecond}, "bucket")
+			fake.RestoreDuration = 20 * time.Millisecond
+			putArchived(t, fake, "bucket", map[string]types.StorageClass{"archived": types.StorageClassDeepArchive, "standard": ""})
+
+			dest := t.TempDir()
+			result, err := client.Copy(context.Background(), "s3://bucket/*", dest)
+			if c.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, c.wantResult.Failed, result.Failed)
+			require.Equal(t, c.wantResult.Skipped, result.Skipped)
+
+			for _, name := range c.wantFiles {
+				_, err := os.Stat(filepath.Join(dest, name))
+				require.NoError(t, err, name)
+			}
+		})
+	}
+}
+
+// orderAPI records the order of restore requests and downloads
+type orderAPI struct {
+	*s3fake.Client
+	mu  sync.Mutex
+	ops []string
+}
+
+func (a *orderAPI) record(op string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.ops = append(a.ops, op)
+}
+
+func (a *orderAPI) RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error) {
+	a.record("RestoreObject")
+	return a.Client.RestoreObject(ctx, params, optFns...)
+}
+
+func (a *orderAPI) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	a.record("GetObject")
+	return a.Client.GetObject(ctx, params, optFns...)
+}
+
+func TestCopyArchivedWaitRequestsRestoresUpFront(t *testing.T) {
+	fake := s3fake.New()
+	fake.CreateBucket("bucket", false)
+	fake.RestoreDuration = 20 * time.Millisecond
+	putArchived(t, fake, "bucket", map[string]types.StorageClass{
+		"a": types.StorageClassGlacier,
+		"b": types.StorageClassGlacier,
+		"c": types.StorageClassDeepArchive,
+	})
+
+	// a single worker would restore objects one by one if it waited for them
+	api := &orderAPI{Client: fake}
+	client := New(api, Options{Concurrency: 1, Archived: ArchivedWait, RestorePollInterval: 5 * time.Millisecond})
+	result, err := client.Copy(context.Background(), "s3://bucket/*", t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, int64(3), result.Completed)
+	require.Equal(t, []string{"RestoreObject", "RestoreObject", "RestoreObject", "GetObject", "GetObject", "GetObject"}, api.ops)
+}
+
+func TestCopyArchivedWaitRestoredWithoutPolling(t *testing.T) {
+	fake := s3fake.New()
+	fake.CreateBucket("bucket", false)
+	putArchived(t, fake, "bucket", map[string]types.StorageClass{
+		"a": types.StorageClassGlacier,
+		"b": types.StorageClassDeepArchive,
+	})
+	for _, key := range []string{"a", "b"} {
+		_, err := fake.RestoreObject(context.Background(), &s3.RestoreObjectInput{Bucket: aws.String("bucket"), Key: aws.String(key)})
+		require.NoError(t, err)
+	}
+
+	// restored objects are downloaded without waiting for the first poll
+	api := &orderAPI{Client: fake}
+	client := New(api, Options{Concurrency: 1, Archived: ArchivedWait, RestorePollInterval: time.Hour})
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	result, err := client.Copy(ctx, "s3://bucket/*", t.TempDir())
+	require.NoError(t, err)
+	require.Equal(t, int64(2), result.Completed)
+	require.Equal(t, []string{"GetObject", "GetObject"}, api.ops)
+}
+
+func TestCopyArchivedSingleObjectSkipped(t *testing.T) {
+	client, fake, recorder := newFakeClient(Options{Archived: ArchivedSkip}, "bucket")
+	putArchived(t, fake, "bucket", map[string]types.StorageClass{"a": types.StorageClassGlacier})
+
+	result, err := client.Copy(context.Background(), "s3://bucket/a", "s3://bucket/b")
+	require.NoError(t, err)
+	require.Equal(t, Result{Skipped: 1}, result)
+	require.Equal(t, StatusSkipped, recorder.events[0].Status)
+	require.True(t, isArchivedError(recorder.events[0].Err))
+}
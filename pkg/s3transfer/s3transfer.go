@@ -0,0 +1,187 @@
+// Package s3transfer is the transfer engine of s3cli. It lists, copies and deletes S3 objects with a pool of
+// workers, reporting an Event for each object and byte level progress to an optional Progress.
+//
+//	client := s3transfer.New(s3.NewFromConfig(cfg), s3transfer.Options{Concurrency: 20})
+//	result, err := client.Copy(ctx, "s3://bucket/prefix/", "/tmp/data")
+package s3transfer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	s3prefix    = "s3://"
+	s3prefixLen = len(s3prefix)
+
+	defaultConcurrency = 10
+)
+
+var ErrNotS3Path = errors.New("not a s3 path")
+var ErrNoBucketFound = errors.New("no bucket found")
+
+// API is the subset of S3 API used for transfers, implemented by *s3.Client
+type API interface {
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObjectAttributes(ctx context.Context, params *s3.GetObjectAttributesInput, optFns ...func(*s3.Options)) (*s3.GetObjectAttributesOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	CopyObject(ctx context.Context, params *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	DeleteObjects(ctx context.Context, params *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	CreateMultipartUpload(ctx context.Context, params *s3.CreateMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CreateMultipartUploadOutput, error)
+	UploadPart(ctx context.Context, params *s3.UploadPartInput, optFns ...func(*s3.Options)) (*s3.UploadPartOutput, error)
+	UploadPartCopy(ctx context.Context, params *s3.UploadPartCopyInput, optFns ...func(*s3.Options)) (*s3.UploadPartCopyOutput, error)
+	CompleteMultipartUpload(ctx context.Context, params *s3.CompleteMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.CompleteMultipartUploadOutput, error)
+	AbortMultipartUpload(ctx context.Context, params *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	RestoreObject(ctx context.Context, params *s3.RestoreObjectInput, optFns ...func(*s3.Options)) (*s3.RestoreObjectOutput, error)
+	GetObjectTagging(ctx context.Context, params *s3.GetObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.GetObjectTaggingOutput, error)
+	PutObjectTagging(ctx context.Context, params *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error)
+	DeleteObjectTagging(ctx context.Context, params *s3.DeleteObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectTaggingOutput, error)
+}
+
+var _ API = (*s3.Client)(nil)
+
+// Options configures a Client, zero value is usable
+type Options struct {
+	// number of requests run in parallel, defaults to 10
+	Concurrency int
+	// timeout of each request, 0 means no timeout
+	RequestTimeout time.Duration
+	// copy objects under a prefix into destination without their directory structure
+	Flatten bool
+	// record modification time, permissions and owner of uploaded files in metadata and restore them on download,
+	// files downloaded without recorded attributes get the modification time of their object
+	Preserve bool
+	// which existing local files and objects copies overwrite. Uploads check objects with conditional
+	// writes, so that objects changed by other writers after being checked are skipped.
+	Overwrite OverwritePolicy
+	// suffix appended to names of files and keys overwritten destinations are kept under, empty does not keep them
+	BackupSuffix string
+	// server side encryption of written objects, also used for reading SSE-C objects
+	Encryption Encryption
+	// attributes of uploaded files and of copies with REPLACE directive, by slash separated path of the file or
+	// source key relative to the copied directory or prefix, the name for single files and objects.
+	// nil only detects content types
+	Attributes func(path string) ObjectAttributes
+	// storage class, access control and tags of uploaded and copied objects
+	Storage StorageOptions
+	// whether copies between S3 paths keep headers and metadata of the source (COPY, default) or take them
+	// from Attributes (REPLACE)
+	MetadataDirective types.MetadataDirective
+	// client side encryption of uploads and decryption of downloads, nil downloads encrypted objects fail
+	ClientEncryption *ClientEncryption
+	// algorithm of checksums computed for uploads and stored with objects, empty disables checksums.
+	// When set, downloads verify checksums of objects and fail for objects without them.
+	Checksum types.ChecksumAlgorithm
+
+	// what downloads and copies do with archived objects that are not restored
+	Archived ArchivedPolicy
+	// restores requested while waiting for archived objects
+	Restore RestoreParams
+	// interval between checks of restores being waited for, defaults to a minute
+	RestorePollInterval time.Duration
+
+	// bandwidth limits in bytes per second shared by all transfers of the client, 0 means unlimited
+	LimitRate         float64
+	LimitUploadRate   float64
+	LimitDownloadRate float64
+
+	// client of the destination endpoint for copies between endpoints, nil when copying within the same endpoint
+	Destination API
+	// receives an event for each transferred or deleted object, called from a single goroutine at a time
+	OnEvent func(Event)
+	// receives byte level progress of transfers, nil disables progress reporting
+	Progress Progress
+}
+
+// Client runs transfers through an API
+type Client struct {
+	api       API
+	dest      *Client
+	opts      Options
+	bandwidth bandwidthLimiters
+	progress  Progress
+	pools     poolRegistry
+}
+
+func New(api API, opts Options) *Client {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultConcurrency
+	}
+
+	c := &Client{
+		api:       api,
+		opts:      opts,
+		bandwidth: newBandwidthLimiters(opts.LimitRate, opts.LimitUploadRate, opts.LimitDownloadRate),
+		progress:  opts.Progress,
+	}
+
+	if c.progress == nil {
+		c.progress = noProgress{}
+	}
+
+	if opts.Destination != nil {
+		// destination shares bandwidth limits and reporting with the source
+		c.dest = &Client{api: opts.Destination, opts: opts, bandwidth: c.bandwidth, progress: c.progress}
+		c.dest.opts.Destination = nil
+	}
+
+	return c
+}
+
+// derive context for a single request applying request timeout
+func (c *Client) requestContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.opts.RequestTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.opts.RequestTimeout)
+}
+
+// client used for writing copies
+func (c *Client) destination() *Client {
+	if c.dest != nil {
+		return c.dest
+	}
+	return c
+}
+
+// IsS3Path reports whether path is of the form s3://bucket/key
+func IsS3Path(path string) bool {
+	return strings.HasPrefix(path, s3prefix)
+}
+
+// ParsePath splits s3://bucket/key into bucket and key
+func ParsePath(path string) (bucket, key string, err error) {
+	if !strings.HasPrefix(path, s3prefix) {
+		return "", "", ErrNotS3Path
+	}
+
+	path = path[s3prefixLen:]
+	idx := strings.IndexRune(path, '/')
+	if idx == -1 && len(path) == 0 {
+		return "", "", ErrNoBucketFound
+	}
+
+	if idx == -1 {
+		return path, "", nil
+	}
+
+	bucket = path[:idx]
+	if len(bucket) == 0 {
+		return "", "", ErrNoBucketFound
+	}
+
+	return path[:idx], path[idx+1:], nil
+}
+
+// S3Path generates s3 path for given bucket and key
+func S3Path(bucket, key string) string {
+	return fmt.Sprintf("%s%s/%s", s3prefix, bucket, key)
+}
@@ -0,0 +1,100 @@
+package s3transfer
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath(t *testing.T) {
+	cases := []struct {
+		name       string
+		input      string
+		wantBucket string
+		wantKey    string
+	}{
+		{"bucket and key", "s3://mybucket/mykey", "mybucket", "mykey"},
+		{"bucket and no key", "s3://mybucket/", "mybucket", ""},
+		{"bucket and no key and no slash at the end", "s3://mybucket", "mybucket", ""},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			gotBucket, gotKey, err := ParsePath(c.input)
+			require.NoError(t, err)
+
+			if gotBucket != c.wantBucket {
+				t.Errorf("got %v want %v", gotBucket, c.wantBucket)
+			}
+
+			if gotKey != c.wantKey {
+				t.Errorf("got %v want %v", gotKey, c.wantKey)
+			}
+		})
+	}
+}
+
+func TestParsePathForError(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   string
+		wantErr error
+	}{
+		{"not s3 path", "/mybucket/mykey", ErrNotS3Path},
+		{"no bucket", "s3://", ErrNoBucketFound},
+		{"empty bucket", "s3:///", ErrNoBucketFound},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, err := ParsePath(c.input)
+
+			if !errors.Is(err, c.wantErr) {
+				t.Errorf("got %v want %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestRunWithErrgroupSkipsQueuedAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	fnch := make(chan func() error, 3)
+	var ran atomic.Int64
+	for i := 0; i < 3; i++ {
+		fnch <- func() error {
+			ran.Add(1)
+			return nil
+		}
+	}
+	cancel()
+	close(fnch)
+
+	var counters poolCounters
+	client := New(nil, Options{})
+	err := client.runWithErrgroup(ctx, fnch, &counters)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), ran.Load())
+	require.Equal(t, int64(3), counters.skipped.Load())
+	require.Equal(t, int64(0), counters.completed.Load())
+}
+
+func TestRequestContext(t *testing.T) {
+	client := New(nil, Options{})
+	ctx, cancel := client.requestContext(context.Background())
+	_, hasDeadline := ctx.Deadline()
+	require.False(t, hasDeadline, "no deadline expected without request timeout")
+	cancel()
+	require.Error(t, ctx.Err())
+
+	client.opts.RequestTimeout = time.Minute
+	ctx, cancel = client.requestContext(context.Background())
+	defer cancel()
+	deadline, hasDeadline := ctx.Deadline()
+	require.True(t, hasDeadline)
+	require.WithinDuration(t, time.Now().Add(time.Minute), deadline, time.Second)
+}
@@ -0,0 +1,90 @@
+package s3transfer
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// StorageOptions are storage class, access control and tags of written objects, zero value uses bucket defaults
+type StorageOptions struct {
+	// storage class i.e. GLACIER_IR or DEEP_ARCHIVE, empty is STANDARD
+	StorageClass types.StorageClass
+	// canned ACL i.e. bucket-owner-full-control
+	ACL types.ObjectCannedACL
+	// grantees of each permission, comma separated as in x-amz-grant-* headers i.e. id=abc, uri=http://...
+	GrantRead        string
+	GrantReadACP     string
+	GrantWriteACP    string
+	GrantFullControl string
+	// url query encoded tags i.e. team=web&env=prod, copies keep source tags when empty
+	Tagging string
+}
+
+// Validate checks storage class, ACL and tagging
+func (o StorageOptions) Validate() error {
+	if len(o.StorageClass) != 0 && !slices.Contains(o.StorageClass.Values(), o.StorageClass) {
+		return fmt.Errorf("unknown storage class %s", o.StorageClass)
+	}
+
+	if len(o.ACL) != 0 && !slices.Contains(o.ACL.Values(), o.ACL) {
+		return fmt.Errorf("unknown canned acl %s", o.ACL)
+	}
+
+	if len(o.Tagging) != 0 {
+		if _, err := url.ParseQuery(o.Tagging); err != nil {
+			return fmt.Errorf("invalid tagging %w", err)
+		}
+	}
+	return nil
+}
+
+// ValidateMetadataDirective checks directive of copies between S3 paths
+func ValidateMetadataDirective(d types.MetadataDirective) error {
+	if len(d) != 0 && !slices.Contains(d.Values(), d) {
+		return errors.New("metadata directive should be COPY or REPLACE")
+	}
+	return nil
+}
+
+func (o StorageOptions) applyToPut(input *s3.PutObjectInput) {
+	input.StorageClass = o.StorageClass
+	input.ACL = o.ACL
+	input.GrantRead = stringOrNil(o.GrantRead)
+	input.GrantReadACP = stringOrNil(o.GrantReadACP)
+	input.GrantWriteACP = stringOrNil(o.GrantWriteACP)
+	input.GrantFullControl = stringOrNil(o.GrantFullControl)
+	if len(o.Tagging) != 0 {
+		input.Tagging = stringOrNil(o.Tagging)
+	}
+}
+
+func (o StorageOptions) applyToCreateMultipart(input *s3.CreateMultipartUploadInput) {
+	input.StorageClass = o.StorageClass
+	input.ACL = o.ACL
+	input.GrantRead = stringOrNil(o.GrantRead)
+	input.GrantReadACP = stringOrNil(o.GrantReadACP)
+	input.GrantWriteACP = stringOrNil(o.GrantWriteACP)
+	input.GrantFullControl = stringOrNil(o.GrantFullControl)
+	if len(o.Tagging) != 0 {
+		input.Tagging = stringOrNil(o.Tagging)
+	}
+}
+
+// copies keep tags of the source unless tagging is given, storage class and ACL are never copied by S3
+func (o StorageOptions) applyToCopy(input *s3.CopyObjectInput) {
+	input.StorageClass = o.StorageClass
+	input.ACL = o.ACL
+	input.GrantRead = stringOrNil(o.GrantRead)
+	input.GrantReadACP = stringOrNil(o.GrantReadACP)
+	input.GrantWriteACP = stringOrNil(o.GrantWriteACP)
+	input.GrantFullControl = stringOrNil(o.GrantFullControl)
+	if len(o.Tagging) != 0 {
+		input.Tagging = stringOrNil(o.Tagging)
+		input.TaggingDirective = types.TaggingDirectiveReplace
+	}
+}
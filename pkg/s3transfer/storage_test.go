@@ -0,0 +1,147 @@
+package s3transfer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/internal/s3fake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStorageOptionsValidate(t *testing.T) {
+	cases := []struct {
+		name    string
+		input   StorageOptions
+		wantErr bool
+	}{
+		{"empty", StorageOptions{}, false},
+		{"all", StorageOptions{StorageClass: types.StorageClassDeepArchive, ACL: types.ObjectCannedACLBucketOwnerFullControl, Tagging: "team=web&env=prod"}, false},
+		{"unknown storage class", StorageOptions{StorageClass: "COLD"}, true},
+		{"unknown acl", StorageOptions{ACL: "everyone"}, true},
+		{"invalid tagging", StorageOptions{Tagging: "team=%zz"}, true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := c.input.Validate(); (err != nil) != c.wantErr {
+				t.Errorf("got %v want error %v", err, c.wantErr)
+			}
+		})
+	}
+}
+
+func TestCopyWithStorageOptions(t *testing.T) {
+	storage := StorageOptions{StorageClass: types.StorageClassGlacierIr, ACL: types.ObjectCannedACLBucketOwnerFullControl, Tagging: "team=web"}
+	client, fake, _ := newFakeClient(Options{Storage: storage}, "bucket")
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{"a": "a"})
+
+	_, err := client.Copy(context.Background(), filepath.Join(src, "a"), "s3://bucket/a")
+	require.NoError(t, err)
+	_, err = client.Copy(context.Background(), "s3://bucket/a", "s3://bucket/b")
+	require.NoError(t, err)
+
+	for _, key := range []string{"a", "b"} {
+		output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String(key)})
+		require.NoError(t, err)
+		require.Equal(t, types.StorageClassGlacierIr, output.StorageClass)
+		require.Equal(t, types.ObjectCannedACLBucketOwnerFullControl, fake.ACL("bucket", key))
+		require.Equal(t, map[string]string{"team": "web"}, fake.Tags("bucket", key))
+	}
+}
+
+func TestCopyKeepsTags(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{}, "bucket")
+	_, err := fake.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String("a"), Tagging: aws.String("team=web")})
+	require.NoError(t, err)
+
+	_, err = client.Copy(context.Background(), "s3://bucket/a", "s3://bucket/b")
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"team": "web"}, fake.Tags("bucket", "b"))
+}
+
+func TestStreamedCopyKeepsTags(t *testing.T) {
+	cases := []struct {
+		name    string
+		tagging string
+		want    map[string]string
+	}{
+		{"source tags", "", map[string]string{"team": "web", "env": "prod"}},
+		{"given tags", "team=ops", map[string]string{"team": "ops"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			src, dest := s3fake.New(), s3fake.New()
+			src.CreateBucket("bucket", false)
+			dest.CreateBucket("bucket", false)
+			_, err := src.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String("a"), Tagging: aws.String("team=web&env=prod")})
+			require.NoError(t, err)
+
+			client := New(src, Options{Destination: dest, Storage: StorageOptions{Tagging: c.tagging}})
+			_, err = client.Copy(context.Background(), "s3://bucket/a", "s3://bucket/b")
+			require.NoError(t, err)
+			require.Equal(t, c.want, dest.Tags("bucket", "b"))
+		})
+	}
+}
+
+func TestCopyWithMetadataDirective(t *testing.T) {
+	attrs := func(string) ObjectAttributes {
+		return ObjectAttributes{CacheControl: "no-cache", Metadata: map[string]string{"team": "web"}}
+	}
+	ce, err := NewClientEncryption(bytes.Repeat([]byte{3}, 32))
+	require.NoError(t, err)
+	ce.EncryptUploads = true
+
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{"a.html": "<p>a</p>"})
+
+	cases := []struct {
+		name      string
+		directive types.MetadataDirective
+		want      map[string]string
+	}{
+		{"copy", types.MetadataDirectiveCopy, map[string]string{"owner": "ops"}},
+		{"replace", types.MetadataDirectiveReplace, map[string]string{"team": "web"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, fake, _ := newFakeClient(Options{Attributes: attrs, MetadataDirective: c.directive}, "bucket")
+			_, err := fake.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String("a.html"), Metadata: map[string]string{"owner": "ops"}})
+			require.NoError(t, err)
+
+			_, err = client.Copy(context.Background(), "s3://bucket/a.html", "s3://bucket/b.html")
+			require.NoError(t, err)
+			require.Equal(t, c.want, fake.Metadata("bucket", "b.html"))
+		})
+	}
+
+	t.Run("replace keeps client encryption", func(t *testing.T) {
+		client, fake, _ := newFakeClient(Options{Attributes: attrs, MetadataDirective: types.MetadataDirectiveReplace, ClientEncryption: ce}, "bucket")
+		_, err := client.Copy(context.Background(), filepath.Join(src, "a.html"), "s3://bucket/a.html")
+		require.NoError(t, err)
+		_, err = client.Copy(context.Background(), "s3://bucket/a.html", "s3://bucket/b.html")
+		require.NoError(t, err)
+
+		output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("b.html")})
+		require.NoError(t, err)
+		require.Equal(t, "no-cache", aws.ToString(output.CacheControl))
+		require.Nil(t, output.ContentType)
+		require.Equal(t, "web", output.Metadata["team"])
+
+		dest := t.TempDir()
+		_, err = client.Copy(context.Background(), "s3://bucket/b.html", dest+string(filepath.Separator))
+		require.NoError(t, err)
+		got, err := os.ReadFile(filepath.Join(dest, "b.html"))
+		require.NoError(t, err)
+		require.Equal(t, "<p>a</p>", string(got))
+	})
+}
@@ -0,0 +1,230 @@
+package s3transfer
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"golang.org/x/sync/errgroup"
+)
+
+// TagAction is a change of object tags
+type TagAction string
+
+const (
+	// replace all tags of objects
+	TagSet TagAction = "set"
+	// add tags to objects, values of existing keys are replaced
+	TagAdd TagAction = "add"
+	// remove tags by key, all tags when no tags are given
+	TagDelete TagAction = "delete"
+)
+
+// ObjectTags are the tags of an object
+type ObjectTags struct {
+	Path string
+	Tags map[string]string
+}
+
+// TagFilter matches objects having tag Key, with value Value unless AnyValue is set
+type TagFilter struct {
+	Key      string
+	Value    string
+	AnyValue bool
+}
+
+func (f TagFilter) matches(tags map[string]string) bool {
+	v, ok := tags[f.Key]
+	return ok && (f.AnyValue || v == f.Value)
+}
+
+// Tags returns tags of objects at s3 paths sorted by path, paths ending with * return tags of all objects
+// under the prefix
+func (c *Client) Tags(ctx context.Context, paths []string) ([]ObjectTags, error) {
+	var mu sync.Mutex
+	var tags []ObjectTags
+	err := c.runForEachObject(ctx, paths, nil, func(ctx context.Context, bucket, key string) error {
+		t, err := c.getTags(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		tags = append(tags, ObjectTags{Path: S3Path(bucket, key), Tags: t})
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Path < tags[j].Path })
+	return tags, nil
+}
+
+// Tag changes tags of objects at s3 paths, paths ending with * change tags of all objects under the prefix
+func (c *Client) Tag(ctx context.Context, paths []string, action TagAction, tags map[string]string) (Result, error) {
+	var result Result
+	switch action {
+	case TagSet, TagAdd, TagDelete:
+	default:
+		return result, fmt.Errorf("unknown tag action %q", action)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	fnch := make(chan func() error, c.opts.Concurrency)
+	evch := make(chan Event, c.opts.Concurrency)
+	run := c.runPooled(ctx, cancel, fnch, evch, &result)
+	work, cancelWork := inFlightContext(ctx)
+	defer cancelWork()
+
+	err := c.forEachObject(ctx, paths, nil, func(bucket, key string) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case fnch <- func() error {
+			start := time.Now()
+			err := c.tagObject(work, bucket, key, action, tags)
+			evch <- newEvent(OpTag, S3Path(bucket, key), "", 0, start, err)
+			return err
+		}:
+			return true
+		}
+	})
+
+	close(fnch)
+	if werr := run.wait(&result); werr != nil {
+		return result, werr
+	}
+	return result, err
+}
+
+func (c *Client) tagObject(ctx context.Context, bucket, key string, action TagAction, tags map[string]string) error {
+	if action == TagSet {
+		return c.putTags(ctx, bucket, key, tags)
+	}
+	if action == TagDelete && len(tags) == 0 {
+		return c.putTags(ctx, bucket, key, nil)
+	}
+
+	current, err := c.getTags(ctx, bucket, key)
+	if err != nil {
+		return err
+	}
+
+	for k, v := range tags {
+		if action == TagAdd {
+			current[k] = v
+		} else {
+			delete(current, k)
+		}
+	}
+	return c.putTags(ctx, bucket, key, current)
+}
+
+func (c *Client) getTags(ctx context.Context, bucket, key string) (map[string]string, error) {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	output, err := c.api.GetObjectTagging(ctx, &s3.GetObjectTaggingInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string]string, len(output.TagSet))
+	for _, t := range output.TagSet {
+		tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	return tags, nil
+}
+
+// url query encoded tagging header of tags
+func encodeTagging(tags map[string]string) string {
+	values := make(url.Values, len(tags))
+	for k, v := range tags {
+		values.Set(k, v)
+	}
+	return values.Encode()
+}
+
+// replace tags of an object, empty tags remove all tags
+func (c *Client) putTags(ctx context.Context, bucket, key string, tags map[string]string) error {
+	ctx, cancel := c.requestContext(ctx)
+	defer cancel()
+
+	if len(tags) == 0 {
+		_, err := c.api.DeleteObjectTagging(ctx, &s3.DeleteObjectTaggingInput{
+			Bucket: aws.String(bucket),
+			Key:    aws.String(key),
+		})
+		return err
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tagging := &types.Tagging{TagSet: make([]types.Tag, 0, len(keys))}
+	for _, k := range keys {
+		tagging.TagSet = append(tagging.TagSet, types.Tag{Key: aws.String(k), Value: aws.String(tags[k])})
+	}
+
+	_, err := c.api.PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+		Bucket:  aws.String(bucket),
+		Key:     aws.String(key),
+		Tagging: tagging,
+	})
+	return err
+}
+
+// objects having tags matching all filters, tags are fetched in parallel and order of objects is kept
+func (c *Client) filterByTags(ctx context.Context, bucket string, objects []types.Object, filters []TagFilter) ([]types.Object, error) {
+	keep := make([]bool, len(objects))
+	errg, ctx := errgroup.WithContext(ctx)
+	errg.SetLimit(c.opts.Concurrency)
+	for i, o := range objects {
+		i, key := i, aws.ToString(o.Key)
+		errg.Go(func() error {
+			tags, err := c.getTags(ctx, bucket, key)
+			if err != nil {
+				return err
+			}
+			keep[i] = MatchesTags(tags, filters)
+			return nil
+		})
+	}
+
+	if err := errg.Wait(); err != nil {
+		return nil, err
+	}
+
+	filtered := objects[:0]
+	for i, o := range objects {
+		if keep[i] {
+			filtered = append(filtered, o)
+		}
+	}
+	return filtered, nil
+}
+
+// MatchesTags reports whether tags match all filters
+func MatchesTags(tags map[string]string, filters []TagFilter) bool {
+	for _, f := range filters {
+		if !f.matches(tags) {
+			return false
+		}
+	}
+	return true
+}
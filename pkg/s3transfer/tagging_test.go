@@ -0,0 +1,94 @@
+package s3transfer
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTag(t *testing.T) {
+	cases := []struct {
+		name   string
+		action TagAction
+		tags   map[string]string
+		want   map[string]string
+	}{
+		{"set", TagSet, map[string]string{"env": "prod"}, map[string]string{"env": "prod"}},
+		{"add", TagAdd, map[string]string{"env": "prod", "team": "data"}, map[string]string{"env": "prod", "team": "data"}},
+		{"delete keys", TagDelete, map[string]string{"team": ""}, map[string]string{"env": "dev"}},
+		{"delete all", TagDelete, nil, nil},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			client, fake, recorder := newFakeClient(Options{}, "bucket")
+			for _, key := range []string{"logs/a", "logs/b"} {
+				_, err := fake.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String(key), Tagging: aws.String("team=web&env=dev")})
+				require.NoError(t, err)
+			}
+			fake.Put("bucket", "other", nil)
+
+			result, err := client.Tag(context.Background(), []string{"s3://bucket/logs/*"}, c.action, c.tags)
+			require.NoError(t, err)
+			require.Equal(t, Result{Completed: 2}, result)
+			require.ElementsMatch(t, []string{"s3://bucket/logs/a", "s3://bucket/logs/b"}, recorder.sources(OpTag))
+
+			for _, key := range []string{"logs/a", "logs/b"} {
+				require.Equal(t, c.want, fake.Tags("bucket", key))
+			}
+			require.Nil(t, fake.Tags("bucket", "other"))
+		})
+	}
+}
+
+func TestTags(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{}, "bucket")
+	_, err := fake.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String("b"), Tagging: aws.String("env=prod&team=web")})
+	require.NoError(t, err)
+	fake.Put("bucket", "a", nil)
+
+	tags, err := client.Tags(context.Background(), []string{"s3://bucket/*"})
+	require.NoError(t, err)
+	require.Equal(t, []ObjectTags{
+		{Path: "s3://bucket/a", Tags: map[string]string{}},
+		{Path: "s3://bucket/b", Tags: map[string]string{"env": "prod", "team": "web"}},
+	}, tags)
+
+	_, err = client.Tags(context.Background(), []string{"s3://bucket/missing"})
+	require.Error(t, err)
+}
+
+func TestListWithTagFilter(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{}, "bucket")
+	fake.PageSize = 2
+	for key, tagging := range map[string]string{"a": "env=prod", "b": "env=dev", "c": "env=prod&team=web", "d": "", "e": "team=web"} {
+		_, err := fake.PutObject(context.Background(), &s3.PutObjectInput{Bucket: aws.String("bucket"), Key: aws.String(key), Tagging: stringOrNil(tagging)})
+		require.NoError(t, err)
+	}
+
+	cases := []struct {
+		name    string
+		filters []TagFilter
+		want    []string
+	}{
+		{"value", []TagFilter{{Key: "env", Value: "prod"}}, []string{"a", "c"}},
+		{"any value", []TagFilter{{Key: "env", AnyValue: true}}, []string{"a", "b", "c"}},
+		{"all filters", []TagFilter{{Key: "env", Value: "prod"}, {Key: "team", Value: "web"}}, []string{"c"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var keys []string
+			err := client.List(context.Background(), ListParams{Bucket: "bucket", Tags: c.filters}, func(output *s3.ListObjectsV2Output) {
+				for _, o := range output.Contents {
+					keys = append(keys, aws.ToString(o.Key))
+				}
+			})
+			require.NoError(t, err)
+			require.Equal(t, c.want, keys)
+		})
+	}
+}
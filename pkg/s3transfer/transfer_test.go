@@ -0,0 +1,419 @@
+package s3transfer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/kullanici0606/s3cli/v2/internal/s3fake"
+	"github.com/stretchr/testify/require"
+)
+
+var _ API = (*s3fake.Client)(nil)
+
+// eventRecorder collects events reported by a client, OnEvent is never called concurrently
+type eventRecorder struct {
+	events []Event
+}
+
+func (r *eventRecorder) record(e Event) {
+	r.events = append(r.events, e)
+}
+
+// sorted sources of events with given operation
+func (r *eventRecorder) sources(op Operation) []string {
+	var sources []string
+	for _, e := range r.events {
+		if e.Op == op {
+			sources = append(sources, e.Source)
+		}
+	}
+	sort.Strings(sources)
+	return sources
+}
+
+func newFakeClient(opts Options, buckets ...string) (*Client, *s3fake.Client, *eventRecorder) {
+	fake := s3fake.New()
+	for _, b := range buckets {
+		fake.CreateBucket(b, false)
+	}
+
+	recorder := &eventRecorder{}
+	opts.OnEvent = recorder.record
+	return New(fake, opts), fake, recorder
+}
+
+func writeTestFiles(t *testing.T, root string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0644))
+	}
+}
+
+func TestListPagination(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{}, "bucket")
+	fake.PageSize = 2
+	for _, k := range []string{"dir/a", "dir/b", "dir/sub/c", "other"} {
+		fake.Put("bucket", k, []byte(k))
+	}
+
+	cases := []struct {
+		name   string
+		params ListParams
+		want   []string
+	}{
+		{"prefix", ListParams{Bucket: "bucket", Prefix: "dir/"}, []string{"dir/a", "dir/b", "dir/sub/c"}},
+		{"delimiter", ListParams{Bucket: "bucket", Prefix: "dir/", Delimiter: "/"}, []string{"dir/a", "dir/b", "dir/sub/"}},
+		{"root", ListParams{Bucket: "bucket", Delimiter: "/"}, []string{"dir/", "other"}},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got []string
+			err := client.List(context.Background(), c.params, func(output *s3.ListObjectsV2Output) {
+				for _, o := range output.Contents {
+					got = append(got, *o.Key)
+				}
+				for _, p := range output.CommonPrefixes {
+					got = append(got, *p.Prefix)
+				}
+			})
+			require.NoError(t, err)
+
+			sort.Strings(got)
+			require.Equal(t, c.want, got)
+		})
+	}
+}
+
+func TestListNoSuchBucket(t *testing.T) {
+	client, _, _ := newFakeClient(Options{})
+	err := client.List(context.Background(), ListParams{Bucket: "missing"}, func(*s3.ListObjectsV2Output) {})
+	require.Error(t, err)
+}
+
+func TestCopyRoundTrip(t *testing.T) {
+	client, fake, recorder := newFakeClient(Options{}, "bucket")
+	files := map[string]string{"a.txt": "a", "sub/b.txt": "bb", "sub/deeper/c.txt": "ccc"}
+
+	src := filepath.Join(t.TempDir(), "data")
+	writeTestFiles(t, src, files)
+
+	result, err := client.Copy(context.Background(), src, "s3://bucket/up/")
+	require.NoError(t, err)
+	require.Equal(t, Result{Completed: 3, Bytes: 6}, result)
+	require.Equal(t, []string{"up/data/a.txt", "up/data/sub/b.txt", "up/data/sub/deeper/c.txt"}, fake.Keys("bucket"))
+
+	dest := t.TempDir()
+	result, err = client.Copy(context.Background(), "s3://bucket/up/data/", dest)
+	require.NoError(t, err)
+	require.Equal(t, Result{Completed: 3, Bytes: 6}, result)
+
+	for name, want := range files {
+		got, err := os.ReadFile(filepath.Join(dest, filepath.FromSlash(name)))
+		require.NoError(t, err)
+		if string(got) != want {
+			t.Errorf("got %q want %q for %s", got, want, name)
+		}
+	}
+
+	require.Len(t, recorder.sources(OpUpload), 3)
+	require.Equal(t, []string{"s3://bucket/up/data/a.txt", "s3://bucket/up/data/sub/b.txt", "s3://bucket/up/data/sub/deeper/c.txt"}, recorder.sources(OpDownload))
+	for _, e := range recorder.events {
+		require.Equal(t, StatusSucceeded, e.Status)
+		if e.Op != OpDownload {
+			continue
+		}
+		want := int64(len(files[strings.TrimPrefix(e.Source, "s3://bucket/up/data/")]))
+		if e.Bytes != want {
+			t.Errorf("got %d bytes want %d for %s", e.Bytes, want, e.Source)
+		}
+	}
+}
+
+func TestCopyFromS3ToS3(t *testing.T) {
+	cases := []struct {
+		name          string
+		crossEndpoint bool
+		wantCopyCalls int
+	}{
+		{"same endpoint", false, 2},
+		{"between endpoints", true, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			src, dest := s3fake.New(), s3fake.New()
+			src.CreateBucket("src", false)
+			opts := Options{}
+			if c.crossEndpoint {
+				opts.Destination = dest
+			} else {
+				dest = src
+			}
+			dest.CreateBucket("dest", false)
+
+			src.Put("src", "prefix/a b", []byte("a"))
+			src.Put("src", "prefix/c", []byte("c"))
+
+			result, err := New(src, opts).Copy(context.Background(), "s3://src/prefix/", "s3://dest/copied")
+			require.NoError(t, err)
+			require.Equal(t, int64(2), result.Completed)
+
+			require.Equal(t, []string{"copied/a b", "copied/c"}, dest.Keys("dest"))
+			require.Equal(t, c.wantCopyCalls, src.Calls("CopyObject"))
+		})
+	}
+}
+
+func TestCopyLargeObjectWithMultipartCopy(t *testing.T) {
+	defer func(size, partSize int64) { maxCopyObjectSize, copyPartSize = size, partSize }(maxCopyObjectSize, copyPartSize)
+	maxCopyObjectSize, copyPartSize = 8, 4
+
+	client, fake, _ := newFakeClient(Options{Checksum: types.ChecksumAlgorithmCrc32}, "bucket")
+	_, err := fake.PutObject(context.Background(), &s3.PutObjectInput{
+		Bucket:       aws.String("bucket"),
+		Key:          aws.String("a"),
+		Body:         strings.NewReader("0123456789"),
+		CacheControl: aws.String("no-cache"),
+		Metadata:     map[string]string{"team": "web"},
+		Tagging:      aws.String("env=prod"),
+	})
+	require.NoError(t, err)
+
+	result, err := client.Copy(context.Background(), "s3://bucket/a", "s3://bucket/b")
+	require.NoError(t, err)
+	require.Equal(t, int64(1), result.Completed)
+
+	data, _ := fake.Get("bucket", "b")
+	require.Equal(t, "0123456789", string(data))
+	require.Equal(t, 0, fake.Calls("CopyObject"))
+	require.Equal(t, 0, fake.Calls("GetObject"))
+	require.Equal(t, 3, fake.Calls("UploadPartCopy"))
+	require.Zero(t, fake.PendingUploads())
+	require.Equal(t, map[string]string{"team": "web"}, fake.Metadata("bucket", "b"))
+	require.Equal(t, map[string]string{"env": "prod"}, fake.Tags("bucket", "b"))
+
+	output, err := fake.HeadObject(context.Background(), &s3.HeadObjectInput{Bucket: aws.String("bucket"), Key: aws.String("b"), ChecksumMode: types.ChecksumModeEnabled})
+	require.NoError(t, err)
+	require.Equal(t, "no-cache", aws.ToString(output.CacheControl))
+	require.True(t, strings.HasSuffix(aws.ToString(output.ChecksumCRC32), "-3"))
+}
+
+// interruptingAPI cancels the run when the first download starts
+type interruptingAPI struct {
+	*s3fake.Client
+	cancel context.CancelFunc
+}
+
+func (a interruptingAPI) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	a.cancel()
+	return a.Client.GetObject(ctx, params, optFns...)
+}
+
+func TestCancelLeavesInFlightTransfers(t *testing.T) {
+	cases := []struct {
+		name          string
+		aborted       bool
+		wantCompleted int64
+		wantFailed    int64
+	}{
+		{"in flight finish", false, 1, 0},
+		{"aborted", true, 0, 1},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			fake := s3fake.New()
+			fake.CreateBucket("bucket", false)
+			for _, k := range []string{"a", "b", "c"} {
+				fake.Put("bucket", k, []byte(k))
+			}
+
+			abort, cancelAbort := context.WithCancel(context.Background())
+			defer cancelAbort()
+			if c.aborted {
+				cancelAbort()
+			}
+			ctx, cancel := context.WithCancel(WithAbort(context.Background(), abort))
+			defer cancel()
+
+			client := New(interruptingAPI{fake, cancel}, Options{Concurrency: 1})
+			result, err := client.Copy(ctx, "s3://bucket/", t.TempDir())
+			if c.aborted {
+				require.ErrorIs(t, err, context.Canceled)
+			} else {
+				require.NoError(t, err)
+			}
+			require.Equal(t, c.wantCompleted, result.Completed)
+			require.Equal(t, c.wantFailed, result.Failed)
+			require.Equal(t, 1, fake.Calls("GetObject"))
+		})
+	}
+}
+
+func TestDownloadErrorLeavesNoFile(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{}, "bucket")
+	fake.Put("bucket", "key", []byte("data"))
+	fake.FailOperation("GetObject", errors.New("connection reset"))
+
+	dest := t.TempDir()
+	_, err := client.Copy(context.Background(), "s3://bucket/key", filepath.Join(dest, "key"))
+	require.Error(t, err)
+
+	entries, err := os.ReadDir(dest)
+	require.NoError(t, err)
+	require.Empty(t, entries)
+}
+
+func TestCopyPrefixStopsOnError(t *testing.T) {
+	client, fake, recorder := newFakeClient(Options{Concurrency: 2}, "bucket")
+	for _, k := range []string{"a", "b", "c", "d"} {
+		fake.Put("bucket", "prefix/"+k, []byte(k))
+	}
+	fake.FailOperation("GetObject", errors.New("access denied"))
+
+	result, err := client.Copy(context.Background(), "s3://bucket/prefix/", t.TempDir())
+	require.ErrorContains(t, err, "access denied")
+
+	require.NotZero(t, result.Failed)
+	for _, e := range recorder.events {
+		require.Equal(t, StatusFailed, e.Status)
+		require.ErrorIs(t, e.Err, err)
+	}
+}
+
+func TestRemoveWithDeleteErrors(t *testing.T) {
+	client, fake, recorder := newFakeClient(Options{}, "bucket")
+	fake.PageSize = 2
+	for _, k := range []string{"logs/1", "logs/2", "logs/3", "logs/locked", "keep", "single"} {
+		fake.Put("bucket", k, []byte(k))
+	}
+	fake.FailDelete("logs/locked", "AccessDenied", "Access Denied")
+
+	result, err := client.Remove(context.Background(), []string{"s3://bucket/logs/*", "s3://bucket/single"})
+	require.NoError(t, err)
+	require.Equal(t, Result{Completed: 4, Failed: 1}, result)
+
+	require.Equal(t, []string{"keep", "logs/locked"}, fake.Keys("bucket"))
+
+	var deleteErr *DeleteError
+	for _, e := range recorder.events {
+		if e.Err != nil {
+			require.Equal(t, "s3://bucket/logs/locked", e.Source)
+			require.ErrorAs(t, e.Err, &deleteErr)
+		}
+	}
+	require.Equal(t, "AccessDenied", deleteErr.Code)
+}
+
+func TestRemoveGlobs(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{Concurrency: 1}, "bucket", "other")
+	for _, k := range []string{"a/1", "a/2", "b/1", "keep"} {
+		fake.Put("bucket", k, []byte(k))
+	}
+	fake.Put("other", "c/1", []byte("c"))
+
+	result, err := client.Remove(context.Background(), []string{"s3://bucket/a/*", "s3://bucket/b/*", "s3://other/c/*"})
+	require.NoError(t, err)
+	require.Equal(t, Result{Completed: 4}, result)
+	require.Equal(t, []string{"keep"}, fake.Keys("bucket"))
+	require.Empty(t, fake.Keys("other"))
+}
+
+func TestUploadConcurrencyLimit(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{Concurrency: 3}, "bucket")
+	fake.Latency = 10 * time.Millisecond
+
+	files := make(map[string]string)
+	for _, name := range strings.Split("abcdefghijkl", "") {
+		files[name] = name
+	}
+	src := t.TempDir()
+	writeTestFiles(t, src, files)
+
+	_, err := client.Copy(context.Background(), src, "s3://bucket/")
+	require.NoError(t, err)
+
+	require.Len(t, fake.Keys("bucket"), len(files))
+	if got := fake.MaxInFlight(); got > 3 || got < 2 {
+		t.Errorf("got %d concurrent requests want between 2 and %d", got, 3)
+	}
+}
+
+func TestCopyCancelledSkipsQueued(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	client, fake, _ := newFakeClient(Options{}, "bucket")
+	src := t.TempDir()
+	writeTestFiles(t, src, map[string]string{"a": "a"})
+
+	_, err := client.Copy(ctx, src, "s3://bucket/")
+	require.NoError(t, err)
+	require.Empty(t, fake.Keys("bucket"))
+}
+
+func TestUploadStreamAbortsFailedMultipartUpload(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{}, "bucket")
+	fake.FailOperation("UploadPart", errors.New("slow down"))
+
+	err := client.uploadStream(context.Background(), "bucket", "key", strings.NewReader("0123456789"), 4, ObjectAttributes{}, writeCondition{})
+	require.Error(t, err)
+	require.Equal(t, 1, fake.Calls("AbortMultipartUpload"))
+	require.Equal(t, 0, fake.PendingUploads())
+	require.Empty(t, fake.Keys("bucket"))
+}
+
+func TestUploadStreamMultipart(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{}, "bucket")
+
+	err := client.uploadStream(context.Background(), "bucket", "key", strings.NewReader("0123456789"), 4, ObjectAttributes{}, writeCondition{})
+	require.NoError(t, err)
+	require.Equal(t, 3, fake.Calls("UploadPart"))
+
+	got, _ := fake.Get("bucket", "key")
+	require.Equal(t, "0123456789", string(got))
+}
+
+func TestPoolStats(t *testing.T) {
+	client, fake, _ := newFakeClient(Options{Concurrency: 2}, "bucket")
+	fake.Latency = 20 * time.Millisecond
+
+	files := make(map[string]string)
+	for _, name := range strings.Split("abcdef", "") {
+		files[name] = name
+	}
+	src := t.TempDir()
+	writeTestFiles(t, src, files)
+
+	done := make(chan struct{})
+	var maxInFlight int64
+	go func() {
+		defer close(done)
+		_, err := client.Copy(context.Background(), src, "s3://bucket/")
+		require.NoError(t, err)
+	}()
+
+loop:
+	for {
+		select {
+		case <-done:
+			break loop
+		case <-time.After(time.Millisecond):
+			maxInFlight = max(maxInFlight, client.PoolStats().InFlight)
+		}
+	}
+
+	require.Equal(t, int64(2), maxInFlight)
+	require.Equal(t, PoolStats{}, client.PoolStats())
+}